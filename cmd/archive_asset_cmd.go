@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"github.com/chain710/immich-cli/client"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net/http"
+)
+
+type archiveAssetCmd struct {
+	unarchive bool
+	chunkSize int
+	preview   int
+	selector  *assetSelector
+
+	client  client.ClientWithResponsesInterface
+	journal *journal
+}
+
+// archiveChunks archives or unarchives ids chunk by chunk, a failed chunk is reported and the rest go on.
+// archived chunks are journaled, so undo can unarchive them
+func (c *archiveAssetCmd) archiveChunks(ctx context.Context, ids []openapi_types.UUID) error {
+	archived := !c.unarchive
+	chunks := chunkIds(ids, c.chunkSize)
+	failed := 0
+	for i, chunk := range chunks {
+		resp, err := c.client.UpdateAssetsWithResponse(ctx, client.UpdateAssetsJSONRequestBody{Ids: chunk, IsArchived: &archived})
+		if err == nil && resp.StatusCode() != http.StatusNoContent {
+			err = newUnexpectedResponse(resp.StatusCode())
+		}
+		if err == nil && archived {
+			err = c.journal.record(journalActionArchive, chunk)
+		}
+		if err != nil {
+			log.Errorf("update chunk %d/%d (%s ... %s) error: %v", i+1, len(chunks), chunk[0], chunk[len(chunk)-1], err)
+			failed++
+		}
+	}
+
+	return newFailureExitError(failed, len(chunks), "chunk(s)")
+}
+
+func (c *archiveAssetCmd) run(cmd *cobra.Command, args []string) error {
+	c.client = newClient()
+	ids, assets, err := c.selector.resolve(cmd, c.client, args)
+	if err != nil {
+		log.Errorf("resolve ids error: %v", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	action := "archive"
+	if c.unarchive {
+		action = "unarchive"
+	}
+	if len(assets) > 0 {
+		printAssetsPreview(cmd.OutOrStdout(), assets, c.preview, action)
+	}
+
+	if c.journal, err = openJournal(cmd.Name()); err != nil {
+		log.Errorf("open journal error: %v", err)
+		return err
+	}
+	defer c.journal.Close()
+
+	cmd.SilenceUsage = true
+	if err := c.archiveChunks(cmd.Context(), ids); err != nil {
+		return err
+	}
+
+	log.Infof("%s %d asset(s)", action, len(ids))
+	return nil
+}
+
+func ArchiveAssetCmd() *cobra.Command {
+	impl := &archiveAssetCmd{selector: newAssetSelector()}
+	cmd := &cobra.Command{
+		Use:   "archive_asset",
+		Short: "archive_asset [id1] [id2] ... , `-` reads ids from stdin, or query flags select assets",
+		RunE:  impl.run,
+	}
+
+	cmd.Flags().BoolVar(&impl.unarchive, "unarchive", false, "unarchive instead of archive")
+	cmd.Flags().IntVar(&impl.chunkSize, "chunk-size", 1000, "num of ids per update request")
+	cmd.Flags().IntVar(&impl.preview, "preview", 10, "num of matching assets to show")
+	impl.selector.addFlags(cmd.Flags(), "archive")
+	return cmd
+}
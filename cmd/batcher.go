@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// batchItem is ids of one group, done is called with result once they are flushed
+type batchItem struct {
+	group []string
+	ids   []openapi_types.UUID
+	bytes int64 // total file size of ids
+	done  func(item batchItem, err error)
+}
+
+// assetBatcher collects ids from groups, and flushes them together when there are `size` ids or every `interval`
+type assetBatcher struct {
+	size     int
+	interval time.Duration
+	flush    func(ctx context.Context, ids []openapi_types.UUID) error
+
+	mu      sync.Mutex // protect pending, count
+	pending []batchItem
+	count   int
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newAssetBatcher(size int, interval time.Duration,
+	flush func(ctx context.Context, ids []openapi_types.UUID) error) *assetBatcher {
+	return &assetBatcher{size: size, interval: interval, flush: flush, stop: make(chan struct{})}
+}
+
+// start flushes pending ids every interval until close
+func (b *assetBatcher) start(ctx context.Context) {
+	if b.interval <= 0 {
+		return
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.flushPending(ctx)
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (b *assetBatcher) add(ctx context.Context, item batchItem) {
+	b.mu.Lock()
+	b.pending = append(b.pending, item)
+	b.count += len(item.ids)
+	full := b.count >= b.size
+	b.mu.Unlock()
+
+	if full {
+		b.flushPending(ctx)
+	}
+}
+
+func (b *assetBatcher) flushPending(ctx context.Context) {
+	b.mu.Lock()
+	items := b.pending
+	b.pending, b.count = nil, 0
+	b.mu.Unlock()
+
+	if len(items) == 0 {
+		return
+	}
+
+	var ids []openapi_types.UUID
+	for _, item := range items {
+		ids = append(ids, item.ids...)
+	}
+
+	log.Debugf("flush %d ids of %d group(s)", len(ids), len(items))
+	err := b.flush(ctx, ids)
+	if err != nil && len(items) > 1 {
+		// find out which groups fail by flushing them one by one
+		log.Warnf("flush %d group(s) error: %v, retry them separately", len(items), err)
+		for _, item := range items {
+			item.done(item, b.flush(ctx, item.ids))
+		}
+		return
+	}
+
+	for _, item := range items {
+		item.done(item, err)
+	}
+}
+
+// batchCloseTimeout bounds the final flush after ctx is cancelled
+const batchCloseTimeout = 30 * time.Second
+
+// close stops timer and flushes what's left. ids left are of groups planned already, so they're flushed even if
+// ctx is cancelled by interrupt, within batchCloseTimeout
+func (b *assetBatcher) close(ctx context.Context) {
+	close(b.stop)
+	b.wg.Wait()
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), batchCloseTimeout)
+		defer cancel()
+	}
+	b.flushPending(ctx)
+}
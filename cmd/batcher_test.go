@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_AssetBatcher(t *testing.T) {
+	bad := uuid.New()
+	var flushes [][]openapi_types.UUID
+	batcher := newAssetBatcher(3, time.Hour, func(_ context.Context, ids []openapi_types.UUID) error {
+		flushes = append(flushes, ids)
+		for _, id := range ids {
+			if id == bad {
+				return errors.New("bad id")
+			}
+		}
+		return nil
+	})
+
+	var mu sync.Mutex
+	results := make(map[string]error)
+	done := func(item batchItem, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[item.group[0]] = err
+	}
+
+	ctx := context.Background()
+	batcher.start(ctx)
+	batcher.add(ctx, batchItem{group: []string{"g1"}, ids: []openapi_types.UUID{uuid.New()}, done: done})
+	batcher.add(ctx, batchItem{group: []string{"g2"}, ids: []openapi_types.UUID{uuid.New(), uuid.New()}, done: done})
+	require.Len(t, flushes, 1)
+	batcher.add(ctx, batchItem{group: []string{"g3"}, ids: []openapi_types.UUID{bad}, done: done})
+	batcher.add(ctx, batchItem{group: []string{"g4"}, ids: []openapi_types.UUID{uuid.New()}, done: done})
+	require.Len(t, flushes, 1)
+	batcher.close(ctx)
+
+	// failed batch is retried group by group
+	require.Len(t, flushes, 4)
+	require.Len(t, flushes[0], 3)
+	require.NoError(t, results["g1"])
+	require.NoError(t, results["g2"])
+	require.Error(t, results["g3"])
+	require.NoError(t, results["g4"])
+}
+
+func Test_AssetBatcherCloseAfterInterrupt(t *testing.T) {
+	var flushErr error
+	batcher := newAssetBatcher(10, time.Hour, func(ctx context.Context, _ []openapi_types.UUID) error {
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	batcher.start(ctx)
+	batcher.add(ctx, batchItem{group: []string{"g1"}, ids: []openapi_types.UUID{uuid.New()},
+		done: func(_ batchItem, err error) { flushErr = err }})
+
+	// planned ids are still flushed
+	cancel()
+	batcher.close(ctx)
+	require.NoError(t, flushErr)
+}
@@ -1,7 +1,12 @@
 package cmd
 
 const (
-	ViperKey_LogLevel = "log-level"
-	ViperKey_API      = "api"
-	ViperKey_APIKey   = "key"
+	ViperKey_LogLevel  = "log-level"
+	ViperKey_API       = "api"
+	ViperKey_APIKey    = "key"
+	ViperKey_Journal   = "journal"
+	ViperKey_MaxDelete = "max-delete"
+
+	ViperKey_ScoreBy     = "score-by"
+	ViperKey_TieBreakers = "tie-breakers"
 )
@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/chain710/immich-cli/client"
 	"github.com/google/uuid"
 	openapi_types "github.com/oapi-codegen/runtime/types"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"io"
 	"net/http"
 )
 
@@ -24,40 +27,123 @@ func strSliceToIds(args []string) ([]openapi_types.UUID, error) {
 	return ids, errors.Join(errs...)
 }
 
+type deleteAssetCmd struct {
+	force     bool
+	chunkSize int
+	preview   int
+	safety    safetyOptions
+	selector  *assetSelector
+
+	client  client.ClientWithResponsesInterface
+	journal *journal
+}
+
+// printPreview prints the first assets and total size of what's going to be deleted
+func (c *deleteAssetCmd) printPreview(w io.Writer, assets []client.AssetResponseDto) {
+	action := "move to trash"
+	if c.force {
+		action = "permanently delete"
+	}
+	printAssetsPreview(w, assets, c.preview, action)
+}
+
+// resolveIds returns ids of args, ids file, or assets matching query with a preview of them
+func (c *deleteAssetCmd) resolveIds(cmd *cobra.Command, args []string) ([]openapi_types.UUID, error) {
+	c.selector.trashed = c.force
+	ids, assets, err := c.selector.resolve(cmd, c.client, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(assets) > 0 {
+		c.printPreview(cmd.OutOrStdout(), assets)
+	}
+	return ids, nil
+}
+
+// deleteIdChunks deletes ids chunk by chunk, a failed chunk is reported and the rest go on
+func deleteIdChunks(ctx context.Context, cli client.ClientWithResponsesInterface, journal *journal,
+	ids []openapi_types.UUID, force bool, chunkSize int) error {
+	chunks := chunkIds(ids, chunkSize)
+	failed := 0
+	for i, chunk := range chunks {
+		log.Debugf("deleting chunk %d/%d of %d assets", i+1, len(chunks), len(chunk))
+		if err := deleteIdChunk(ctx, cli, journal, chunk, force); err != nil {
+			log.Errorf("delete chunk %d/%d (%s ... %s) error: %v", i+1, len(chunks), chunk[0], chunk[len(chunk)-1], err)
+			failed++
+		}
+	}
+
+	return newFailureExitError(failed, len(chunks), "chunk(s)")
+}
+
+func deleteIdChunk(ctx context.Context, cli client.ClientWithResponsesInterface, journal *journal,
+	ids []openapi_types.UUID, force bool) error {
+	body := client.DeleteAssetsJSONRequestBody{
+		Force: &force,
+		Ids:   ids,
+	}
+
+	response, err := cli.DeleteAssetsWithResponse(ctx, body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusNoContent {
+		return newUnexpectedResponse(response.StatusCode())
+	}
+
+	return journal.record(deleteAction(force), ids)
+}
+
+func (c *deleteAssetCmd) run(cmd *cobra.Command, args []string) error {
+	c.client = newClient()
+	ids, err := c.resolveIds(cmd, args)
+	if err != nil {
+		log.Errorf("resolve ids error: %v", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if ok, err := c.safety.confirmDelete(cmd, len(ids), c.force, fmt.Sprintf("%d asset(s)", len(ids))); err != nil {
+		return err
+	} else if !ok {
+		log.Infof("aborted")
+		return nil
+	}
+
+	c.journal, err = openJournal(cmd.Name())
+	if err != nil {
+		log.Errorf("open journal error: %v", err)
+		return err
+	}
+	defer c.journal.Close()
+
+	log.Debugf("ready to delete %d assets", len(ids))
+	cmd.SilenceUsage = true
+	if err := deleteIdChunks(cmd.Context(), c.client, c.journal, ids, c.force, c.chunkSize); err != nil {
+		return err
+	}
+
+	log.Debugf("delete ok")
+	return nil
+}
+
 func DeleteAssetCmd() *cobra.Command {
-	var force bool
+	impl := &deleteAssetCmd{selector: newAssetSelector()}
+
 	cmd := &cobra.Command{
 		Use:   "delete_asset",
-		Short: "delete_asset [id1] [id2] ...",
-		Args:  cobra.MinimumNArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			ids, err := strSliceToIds(args)
-			if err != nil {
-				log.Errorf("malformed ids: %v", err)
-				return err
-			}
-
-			cli := newClient()
-			body := client.DeleteAssetsJSONRequestBody{
-				Force: &force,
-				Ids:   ids,
-			}
-
-			log.Debugf("ready to delete %d assets", len(ids))
-			response, err := cli.DeleteAssetsWithResponse(cmd.Context(), body)
-			if err != nil {
-				log.Errorf("delete asset error: %v", err)
-				return err
-			}
-			if response.StatusCode() != http.StatusNoContent {
-				return newUnexpectedResponse(response.StatusCode())
-			}
-
-			log.Debugf("delete ok")
-			return nil
-		},
-	}
-
-	cmd.Flags().BoolVar(&force, "force", false, "force delete")
+		Short: "delete_asset [id1] [id2] ... , `-` reads ids from stdin, or query flags select assets",
+		RunE:  impl.run,
+	}
+
+	cmd.Flags().BoolVar(&impl.force, "force", false, "force delete")
+	cmd.Flags().IntVar(&impl.chunkSize, "chunk-size", 1000, "num of ids per delete request")
+	addSafetyFlags(cmd.Flags(), &impl.safety)
+	cmd.Flags().IntVar(&impl.preview, "preview", 10, "num of matching assets to show before confirmation")
+	impl.selector.addFlags(cmd.Flags(), "delete")
 	return cmd
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/chain710/immich-cli/client"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Confirm(t *testing.T) {
+	for input, want := range map[string]bool{"y\n": true, " YES ": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		ok, err := confirm(strings.NewReader(input), &out, "continue?")
+		require.NoError(t, err)
+		require.Equal(t, want, ok, input)
+		require.Equal(t, "continue? [y/N] ", out.String())
+	}
+
+	_, err := confirm(strings.NewReader("y\n"), errWriter{}, "continue?")
+	require.Error(t, err)
+}
+
+// errWriter fails every write
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func Test_DeleteAssetQuery(t *testing.T) {
+	size := int64(1 << 20)
+	assets := []client.AssetResponseDto{
+		{Id: "a", Type: client.AssetTypeEnumVIDEO, ExifInfo: &client.ExifResponseDto{FileSizeInByte: &size}},
+		{Id: "b", Type: client.AssetTypeEnumIMAGE},
+		{Id: "c", Type: client.AssetTypeEnumVIDEO, IsTrashed: true},
+	}
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/search" {
+			total := 1
+			if r.URL.Query().Get("q") == "many" {
+				total = 5
+			}
+			_ = json.NewEncoder(w).Encode(client.SearchResponseDto{
+				Assets: client.SearchAssetResponseDto{Items: assets[1:2], Count: 1, Total: total},
+			})
+			return
+		}
+		if r.URL.Query().Get("skip") != "0" {
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(assets)
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithResponses(server.URL)
+	require.NoError(t, err)
+
+	newCmd := func(args ...string) *deleteAssetCmd {
+		c := &deleteAssetCmd{client: cli, preview: 1, selector: newAssetSelector()}
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		flags.BoolVar(&c.force, "force", false, "")
+		c.selector.addFlags(flags, "delete")
+		require.NoError(t, flags.Parse(args))
+		c.selector.trashed = c.force
+		return c
+	}
+
+	require.False(t, newCmd().selector.isQuery())
+
+	c := newCmd(`--where=type == "VIDEO"`)
+	require.True(t, c.selector.isQuery())
+	matched, err := c.selector.query(context.Background(), cli)
+	require.NoError(t, err)
+	require.Len(t, matched, 1)
+	require.Equal(t, "a", matched[0].Id)
+
+	matched, err = newCmd(`--where=type == "VIDEO"`, "--force").selector.query(context.Background(), cli)
+	require.NoError(t, err)
+	require.Len(t, matched, 2)
+
+	var out bytes.Buffer
+	newCmd("--force").printPreview(&out, assets)
+	require.Contains(t, out.String(), "... and 2 more\n")
+	require.Contains(t, out.String(), "permanently delete 3 asset(s), 1.0 MiB in total\n")
+
+	paths = nil
+	matched, err = newCmd("--search.q=cat").selector.query(context.Background(), cli)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/search?q=cat"}, paths)
+	require.Equal(t, "b", matched[0].Id)
+
+	_, err = newCmd("--search.q=cat", "--isFavorite=true").selector.query(context.Background(), cli)
+	require.Error(t, err)
+
+	// search result is truncated
+	_, err = newCmd("--search.q=many").selector.query(context.Background(), cli)
+	require.ErrorContains(t, err, "matches 5 assets but returns 1")
+}
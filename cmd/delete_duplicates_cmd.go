@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,11 +11,14 @@ import (
 	openapi_types "github.com/oapi-codegen/runtime/types"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io"
 	"net/http"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 type deleteDuplicatesCmd struct {
@@ -23,17 +27,66 @@ type deleteDuplicatesCmd struct {
 	archive    bool
 	concurrent int
 	force      bool
+	explain    bool
 
-	client client.ClientWithResponsesInterface
-	queue  chan []string
+	mergeMetadata bool
+	interactive   bool
+	decisionsFile string
+	reportFile    string
+
+	format         string
+	matchNames     bool
+	unresolvedFile string
+	resume         bool
+	batchSize      int
+	batchInterval  time.Duration
+	summaryFile    string
+	ownerOnly      bool
+	protectShared  bool
+	safety         safetyOptions
+
+	client  client.ClientWithResponsesInterface
+	queue   chan []string
+	journal *journal
+	scorer  *assetScorer
+	out     io.Writer
+	outMu   sync.Mutex // protect out
+
+	livePhotosOnce sync.Once
+	livePhotos     *livePhotoIndex
+	livePhotosErr  error
+
+	decisions *reviewDecisions
+	report    *duplicatesReport
+	state     *runState
+	batcher   *assetBatcher
+	guard     *deleteGuard
+
+	mu       sync.Mutex // protect failures, summary
+	failures []groupFailure
+	summary  runSummary
+}
+
+type groupFailure struct {
+	group []string
+	err   error
 }
 
 type assetQuality struct {
-	id    openapi_types.UUID
-	score int64 // score of quality, higher is better
+	id      openapi_types.UUID
+	asset   *client.AssetResponseDto
+	score   float64 // score of quality, higher is better
+	explain []string
 }
 
-func (c *deleteDuplicatesCmd) processGroup(ctx context.Context, group []string) error {
+// groupPlan is the scored members of a duplicate group, sorted by quality. assets[0] is the keeper
+type groupPlan struct {
+	group  []string
+	assets []assetQuality
+}
+
+// planGroup fetches and scores assets of group
+func (c *deleteDuplicatesCmd) planGroup(ctx context.Context, group []string) (*groupPlan, error) {
 	var errs []error
 	var assets []assetQuality
 	for _, assetId := range group {
@@ -47,28 +100,228 @@ func (c *deleteDuplicatesCmd) processGroup(ctx context.Context, group []string)
 	}
 
 	if len(assets) == 0 {
-		return errors.Join(errs...)
+		return nil, errors.Join(errs...)
 	}
 
-	sort.Slice(assets, func(i, j int) bool {
-		return assets[i].score > assets[j].score
+	livePhotos, err := c.livePhotoIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assets = foldLivePhotos(assets, livePhotos)
+	assets, others := c.guard.filterOwned(assets)
+	if len(others) > 0 {
+		c.recordGuard(group, others, guardReasonNotOwned)
+	}
+	if len(assets) == 0 {
+		return nil, errGroupSkipped
+	}
+
+	sort.SliceStable(assets, func(i, j int) bool {
+		return c.scorer.better(&assets[i], &assets[j])
 	})
 
 	log.DebugFn(func() []interface{} {
 		var values []any
 		for _, asset := range assets {
-			values = append(values, fmt.Sprintf("%s: %d,", asset.id.String(), asset.score))
+			values = append(values, fmt.Sprintf("%s: %g,", asset.id.String(), asset.score))
 		}
 
 		return values
 	})
-	// keep: assets[0], delete others
+	return &groupPlan{group: group, assets: assets}, nil
+}
+
+var errGroupSkipped = errors.New("group skipped")
+
+// processGroup returns ids to be deleted of group, or errGroupSkipped if group is not reviewed
+func (c *deleteDuplicatesCmd) processGroup(ctx context.Context, group []string) (batchItem, error) {
+	item := batchItem{group: group, done: c.finishGroup}
+	plan, err := c.planGroup(ctx, group)
+	if err != nil {
+		return item, err
+	}
+
+	if c.decisions != nil {
+		decision, ok := c.decisions.get(group)
+		if !ok || decision.Skip {
+			log.Infof("skip group %v, reviewed: %v", group, ok)
+			return item, errGroupSkipped
+		}
+
+		if err := plan.keep(decision.Keeper); err != nil {
+			return item, err
+		}
+		// members may change since review, e.g. ownership or live photos, only what was reviewed is deleted
+		if losers := plan.loserIds(0); groupKey(losers) != groupKey(decision.Delete) {
+			return item, fmt.Errorf("group changed since review, would delete %v but reviewed %v, review it again",
+				losers, decision.Delete)
+		}
+	}
+
+	if c.explain {
+		c.explainGroup(plan.assets)
+	}
+	if c.report != nil {
+		c.report.add(ctx, plan, c.guard)
+	}
+
+	item.ids, item.bytes, err = c.applyPlan(ctx, plan)
+	return item, err
+}
+
+// livePhotoIndex loads live photos of library once, it's needed to tell motion videos from plain ones
+func (c *deleteDuplicatesCmd) livePhotoIndex(ctx context.Context) (*livePhotoIndex, error) {
+	c.livePhotosOnce.Do(func() {
+		if c.livePhotos, c.livePhotosErr = loadLivePhotoIndex(ctx, c.client); c.livePhotosErr != nil {
+			log.Errorf("load live photos error: %v", c.livePhotosErr)
+		}
+	})
+	return c.livePhotos, c.livePhotosErr
+}
+
+// foldLivePhotos drops motion videos of live photos, so a live photo is scored, kept or deleted as one unit
+// by its still. motion video whose still is not in assets is dropped too, it's left to its still, otherwise
+// it could be deleted while the still is kept
+func foldLivePhotos(assets []assetQuality, index *livePhotoIndex) []assetQuality {
+	motions := make(map[string]bool)
+	for _, aq := range assets {
+		if aq.asset.LivePhotoVideoId != nil {
+			motions[*aq.asset.LivePhotoVideoId] = true
+		}
+	}
+
+	var folded []assetQuality
+	for _, aq := range assets {
+		if motions[aq.asset.Id] {
+			log.Debugf("fold motion video %s into its live photo", aq.asset.Id)
+			continue
+		}
+		if index.isMotionVideo(aq.asset.Id) {
+			log.Infof("skip motion video %s, its live photo %s is not in the group", aq.asset.Id, index.stills[aq.asset.Id])
+			continue
+		}
+		folded = append(folded, aq)
+	}
+
+	return folded
+}
+
+// keep makes asset of id the keeper
+func (p *groupPlan) keep(id string) error {
+	for i := range p.assets {
+		if p.assets[i].asset.Id == id {
+			keeper := p.assets[i]
+			copy(p.assets[1:i+1], p.assets[:i])
+			p.assets[0] = keeper
+			return nil
+		}
+	}
+
+	return fmt.Errorf("keeper %s not found in group %v", id, p.group)
+}
+
+// loserIds returns ids of members except assets[keeper]
+func (p *groupPlan) loserIds(keeper int) []string {
+	var ids []string
+	for i := range p.assets {
+		if i != keeper {
+			ids = append(ids, p.assets[i].asset.Id)
+		}
+	}
+	return ids
+}
+
+// lostMotionVideos returns motion videos of losers' live photos, which should be deleted along with them.
+// motion video of keeper is never included
+func lostMotionVideos(keeper *client.AssetResponseDto, losers []*client.AssetResponseDto) ([]openapi_types.UUID, error) {
+	var ids []openapi_types.UUID
+	seen := make(map[string]bool)
+	if keeper.LivePhotoVideoId != nil {
+		seen[*keeper.LivePhotoVideoId] = true
+	}
+
+	for _, loser := range losers {
+		if loser.LivePhotoVideoId == nil || seen[*loser.LivePhotoVideoId] {
+			continue
+		}
+
+		id, err := uuid.Parse(*loser.LivePhotoVideoId)
+		if err != nil {
+			return nil, fmt.Errorf("malform live photo video id of %s: `%s`", loser.Id, *loser.LivePhotoVideoId)
+		}
+		seen[*loser.LivePhotoVideoId] = true
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// applyPlan keeps assets[0], merges metadata of others into it,
+// returns ids of others to be deleted and their total file size
+func (c *deleteDuplicatesCmd) applyPlan(ctx context.Context,
+	plan *groupPlan) ([]openapi_types.UUID, int64, error) {
+	assets := plan.assets
 	var ids []openapi_types.UUID
+	var losers []*client.AssetResponseDto
+	var bytes int64
+	var protected []string
 	for _, asset := range assets[1:] {
+		if c.guard.isProtected(asset.asset) {
+			protected = append(protected, asset.asset.Id)
+			continue
+		}
+
 		ids = append(ids, asset.id)
+		losers = append(losers, asset.asset)
+		if asset.asset.ExifInfo != nil && asset.asset.ExifInfo.FileSizeInByte != nil {
+			bytes += *asset.asset.ExifInfo.FileSizeInByte
+		}
+	}
+
+	if len(protected) > 0 {
+		c.recordGuard(plan.group, protected, guardReasonShared)
+	}
+
+	motionIds, err := lostMotionVideos(assets[0].asset, losers)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, id := range motionIds {
+		bytes += c.assetSize(ctx, id)
+	}
+	ids = append(ids, motionIds...)
+	if len(ids) == 0 {
+		return nil, 0, nil
+	}
+
+	if c.mergeMetadata && !c.dryRun && len(losers) > 0 {
+		if err := mergeMetadata(ctx, c.client, assets[0].asset, losers); err != nil {
+			log.Warnf("merge metadata into %s error, skip deleting its duplicates: %v", assets[0].id.String(), err)
+			return nil, 0, err
+		}
+	}
+
+	for _, id := range ids {
+		log.Infof("Should delete asset: `%s`, dryRun: %v", id.String(), c.dryRun)
+	}
+	return ids, bytes, nil
+}
+
+// assetSize returns file size of asset, 0 if it's unknown
+func (c *deleteDuplicatesCmd) assetSize(ctx context.Context, id openapi_types.UUID) int64 {
+	response, err := c.client.GetAssetByIdWithResponse(ctx, id, &client.GetAssetByIdParams{})
+	if err == nil && response.JSON200 == nil {
+		err = newUnexpectedResponse(response.StatusCode())
+	}
+	if err != nil {
+		log.Warnf("get size of asset %s error: %v", id.String(), err)
+		return 0
 	}
 
-	return c.deleteAsset(ctx, ids)
+	if exif := response.JSON200.ExifInfo; exif != nil && exif.FileSizeInByte != nil {
+		return *exif.FileSizeInByte
+	}
+	return 0
 }
 
 func (c *deleteDuplicatesCmd) getAssetQuality(ctx context.Context,
@@ -86,24 +339,30 @@ func (c *deleteDuplicatesCmd) getAssetQuality(ctx context.Context,
 		return nil, newUnexpectedResponse(response.StatusCode())
 	}
 
-	var score int64
-	if response.JSON200.ExifInfo != nil && response.JSON200.ExifInfo.FileSizeInByte != nil {
-		score = *response.JSON200.ExifInfo.FileSizeInByte
-	}
+	score, explain := c.scorer.score(response.JSON200)
+	return &assetQuality{id: assetUUID, asset: response.JSON200, score: score, explain: explain}, nil
+}
 
-	// prefer heic
-	if strings.HasSuffix(strings.ToLower(response.JSON200.OriginalPath), ".heic") {
-		score = score * 10
+// explainGroup prints why assets[0] is kept, assets should be sorted
+func (c *deleteDuplicatesCmd) explainGroup(assets []assetQuality) {
+	var sb strings.Builder
+	for i, asset := range assets {
+		mark := "delete"
+		if i == 0 {
+			mark = "keep"
+		}
+		_, _ = fmt.Fprintf(&sb, "%-6s %s score=%g (%s) %s\n", mark, asset.id.String(), asset.score,
+			strings.Join(asset.explain, ", "), asset.asset.OriginalPath)
 	}
 
-	return &assetQuality{id: assetUUID, score: score}, nil
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	_, _ = fmt.Fprintln(c.out, sb.String())
 }
 
+// deleteAsset archives or deletes ids, it's the flush of batcher
 func (c *deleteDuplicatesCmd) deleteAsset(ctx context.Context, ids []openapi_types.UUID) error {
 	defer func() { log.Debugf("Done!") }()
-	for _, id := range ids {
-		log.Infof("Should delete asset: `%s`, dryRun: %v", id.String(), c.dryRun)
-	}
 	if c.archive {
 		log.Debugf("ready to archive assets...")
 		body := client.UpdateAssetsJSONRequestBody{
@@ -120,71 +379,324 @@ func (c *deleteDuplicatesCmd) deleteAsset(ctx context.Context, ids []openapi_typ
 			return newUnexpectedResponse(response.StatusCode())
 		}
 
-		return nil
-	} else if !c.dryRun {
-		log.Debugf("ready to delete assets...")
-		body := client.DeleteAssetsJSONRequestBody{
-			Force: &c.force,
-			Ids:   ids,
+		return c.journal.record(journalActionArchive, ids)
+	}
+
+	log.Debugf("ready to delete assets...")
+	body := client.DeleteAssetsJSONRequestBody{
+		Force: &c.force,
+		Ids:   ids,
+	}
+	response, err := c.client.DeleteAssetsWithResponse(ctx, body)
+	if err != nil {
+		log.Errorf("delete assets error: %v", err)
+		return err
+	}
+	if response.StatusCode() != http.StatusNoContent {
+		return newUnexpectedResponse(response.StatusCode())
+	}
+
+	return c.journal.record(deleteAction(c.force), ids)
+}
+
+// recordGuard reports assets of group protected by guard
+func (c *deleteDuplicatesCmd) recordGuard(group []string, assets []string, reason string) {
+	log.Warnf("guard kicked in for group %v, keep %s assets: %v", group, reason, assets)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summary.Guarded = append(c.summary.Guarded, guardEvent{Group: group, Assets: assets, Reason: reason})
+}
+
+// finishGroup records result of group
+func (c *deleteDuplicatesCmd) finishGroup(item batchItem, err error) {
+	c.mu.Lock()
+	if errors.Is(err, errGroupSkipped) {
+		c.summary.Skipped++
+		c.mu.Unlock()
+		return
+	}
+
+	c.summary.Processed++
+	if err != nil {
+		c.summary.Failed++
+		c.failures = append(c.failures, groupFailure{group: item.group, err: err})
+	} else {
+		c.summary.Kept++
+		switch {
+		case c.dryRun && c.archive:
+			c.summary.WouldArchive += len(item.ids)
+		case c.dryRun:
+			c.summary.WouldDelete += len(item.ids)
+			c.summary.WouldReclaimBytes += item.bytes
+		case c.archive:
+			c.summary.Archived += len(item.ids)
+		default:
+			c.summary.Deleted += len(item.ids)
+			c.summary.BytesReclaimed += item.bytes
 		}
-		response, err := c.client.DeleteAssetsWithResponse(ctx, body)
-		if err != nil {
-			log.Errorf("delete assets error: %v", err)
-			return err
+	}
+	c.mu.Unlock()
+
+	if c.state != nil {
+		if err := c.state.record(item.group, err); err != nil {
+			log.Errorf("record state of group %v error: %v", item.group, err)
 		}
-		if response.StatusCode() != http.StatusNoContent {
-			return newUnexpectedResponse(response.StatusCode())
+	}
+}
+
+// confirmDelete counts assets of pending groups except one keeper each, plus motion videos deleted with
+// their stills, which is the most it may delete
+func (c *deleteDuplicatesCmd) confirmDelete(cmd *cobra.Command, duplicates [][]string) (bool, error) {
+	index, err := c.livePhotoIndex(cmd.Context())
+	if err != nil {
+		return false, err
+	}
+
+	groups, assets := 0, 0
+	for _, group := range duplicates {
+		if len(group) < 2 || (c.state != nil && c.state.done(group)) {
+			continue
 		}
 
-		return nil
+		// motion videos are folded into their stills, they're deleted with them
+		stills, videos := 0, 0
+		for _, id := range group {
+			if index.isMotionVideo(id) {
+				continue
+			}
+			stills++
+			if _, ok := index.videos[id]; ok {
+				videos++
+			}
+		}
+		if stills < 2 {
+			continue
+		}
+		groups++
+		assets += stills - 1 + videos
 	}
 
-	return nil
+	if assets == 0 {
+		return true, nil
+	}
+	return c.safety.confirmDelete(cmd, assets, c.force, fmt.Sprintf("up to %d asset(s) in %d group(s)", assets, groups))
 }
 
 func (c *deleteDuplicatesCmd) run(cmd *cobra.Command, _ []string) error {
+	if c.concurrent < 1 {
+		return fmt.Errorf("--concurrent must be at least 1, got %d", c.concurrent)
+	}
+
+	scorer, err := newAssetScorer(viper.GetStringSlice(ViperKey_ScoreBy), viper.GetStringSlice(ViperKey_TieBreakers))
+	if err != nil {
+		log.Errorf("malformed score rules: %v", err)
+		return err
+	}
+
+	c.scorer = scorer
+	c.out = cmd.OutOrStdout()
 	c.queue = make(chan []string, c.concurrent)
 	c.client = newClient()
+	if c.journal, err = openJournal(cmd.Name()); err != nil {
+		log.Errorf("open journal error: %v", err)
+		return err
+	}
+	defer c.journal.Close()
 
-	file, err := os.Open(c.database)
-	if err != nil {
-		log.Errorf("open `%s` error: %v", c.database, err)
+	if c.guard, err = newDeleteGuard(cmd.Context(), c.client, c.ownerOnly, c.protectShared); err != nil {
+		log.Errorf("create guard error: %v", err)
 		return err
 	}
-	defer file.Close()
-	decoder := json.NewDecoder(file)
-	var duplicates [][]string
-	if err := decoder.Decode(&duplicates); err != nil {
-		log.Errorf("decode duplicates error: %v", err)
+
+	duplicates, err := c.loadDuplicates(cmd.Context())
+	if err != nil {
 		return err
 	}
 
-	var mu sync.Mutex // protect errs
-	var errs []error
+	if c.decisionsFile != "" {
+		if c.decisions, err = loadReviewDecisions(c.decisionsFile); err != nil {
+			log.Errorf("load decisions error: %v", err)
+			return err
+		}
+	}
+
+	if c.interactive {
+		if c.decisions == nil {
+			return errors.New("--interactive requires --decisions")
+		}
+		return c.review(cmd, duplicates)
+	}
+
+	if c.reportFile != "" {
+		if c.report, err = newDuplicatesReport(c.reportFile, c.client, c.dryRun, duplicates); err != nil {
+			log.Errorf("create report `%s` error: %v", c.reportFile, err)
+			return err
+		}
+		defer func() {
+			if err := c.report.close(); err != nil {
+				log.Errorf("write report `%s` error: %v", c.reportFile, err)
+			} else {
+				log.Infof("report written to %s", c.reportFile)
+			}
+		}()
+	}
+
+	// dry run changes nothing, so it must not mark groups done
+	if !c.dryRun {
+		statePath := c.database + ".state"
+		if c.state, err = openRunState(statePath, c.resume); err != nil {
+			log.Errorf("open state error: %v", err)
+			return err
+		}
+		defer c.state.Close()
+	}
+
+	if !c.dryRun && !c.archive {
+		if ok, err := c.confirmDelete(cmd, duplicates); err != nil {
+			return err
+		} else if !ok {
+			log.Infof("aborted")
+			return nil
+		}
+	}
+
+	c.summary.DryRun, c.summary.Groups, c.summary.StartedAt = c.dryRun, len(duplicates), time.Now()
+	c.batcher = newAssetBatcher(c.batchSize, c.batchInterval, c.deleteAsset)
+	c.batcher.start(cmd.Context())
 	var wg sync.WaitGroup
 	for i := 0; i < c.concurrent; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for group := range c.queue {
-				if err := c.processGroup(cmd.Context(), group); err != nil {
-					mu.Lock()
-					errs = append(errs, err)
-					mu.Unlock()
+				item, err := c.processGroup(cmd.Context(), group)
+				if err != nil || len(item.ids) == 0 || c.dryRun {
+					c.finishGroup(item, err)
+					continue
 				}
+
+				c.batcher.add(cmd.Context(), item)
 			}
 		}()
 	}
 
+feed:
 	for _, group := range duplicates {
-		c.queue <- group
+		if c.state != nil && c.state.done(group) {
+			log.Debugf("skip done group %v", group)
+			c.mu.Lock()
+			c.summary.Skipped++
+			c.mu.Unlock()
+			continue
+		}
+
+		select {
+		case c.queue <- group:
+		case <-cmd.Context().Done():
+			log.Warnf("interrupted, waiting for processing groups...")
+			break feed
+		}
 	}
 
 	close(c.queue)
 	wg.Wait()
-	if len(errs) > 0 {
-		log.Warnf("%d error(s) occured during process, see log for more details", len(errs))
+	c.batcher.close(cmd.Context())
+	if len(c.failures) > 0 {
+		log.Warnf("%d group(s) failed during process, see log for more details", len(c.failures))
+		for _, failure := range c.failures {
+			log.Warnf("group %v failed: %v", failure.group, failure.err)
+		}
 	}
+	if c.state != nil {
+		done, failed, pending := c.state.count(duplicates)
+		log.Infof("groups done: %d, failed: %d, pending: %d", done, failed, pending)
+		if failed+pending > 0 {
+			log.Infof("run with --resume to retry failed and pending groups")
+		}
+	}
+
+	c.summary.Pending = c.summary.Groups - c.summary.Processed - c.summary.Skipped
+	c.summary.Interrupted = cmd.Context().Err() != nil && c.summary.Pending > 0
+	c.summary.FinishedAt = time.Now()
+	if c.summaryFile != "" {
+		if err := c.summary.write(c.summaryFile, cmd.OutOrStdout()); err != nil {
+			log.Errorf("write summary `%s` error: %v", c.summaryFile, err)
+			return err
+		}
+	}
+
+	if err := c.summary.exitError(); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	return nil
+}
+
+// loadDuplicates reads database and resolves file paths in it to asset ids
+func (c *deleteDuplicatesCmd) loadDuplicates(ctx context.Context) ([][]string, error) {
+	duplicates, err := readDuplicates(c.database, c.format)
+	if err != nil {
+		log.Errorf("read duplicates `%s` error: %v", c.database, err)
+		return nil, err
+	}
+
+	duplicates, unresolved, err := resolveDuplicates(ctx, c.client, duplicates, c.matchNames)
+	if err != nil {
+		log.Errorf("resolve duplicates error: %v", err)
+		return nil, err
+	}
+
+	if len(unresolved) > 0 {
+		log.Warnf("%d entries can't be resolved to assets", len(unresolved))
+		for _, entry := range unresolved {
+			log.Debugf("unresolved entry of group %d `%s`: %s", entry.Group, entry.Entry, entry.Reason)
+		}
+	}
+
+	if c.unresolvedFile != "" {
+		data, err := json.MarshalIndent(unresolved, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(c.unresolvedFile, data, 0o644); err != nil {
+			log.Errorf("write unresolved report `%s` error: %v", c.unresolvedFile, err)
+			return nil, err
+		}
+	}
+
+	return duplicates, nil
+}
+
+// review walks through groups not reviewed yet and saves operator's decisions, nothing is deleted
+func (c *deleteDuplicatesCmd) review(cmd *cobra.Command, duplicates [][]string) error {
+	in := bufio.NewReader(cmd.InOrStdin())
+	out := cmd.OutOrStdout()
+	for i, group := range duplicates {
+		if _, ok := c.decisions.get(group); ok {
+			continue
+		}
+
+		plan, err := c.planGroup(cmd.Context(), group)
+		if err != nil {
+			log.Warnf("skip group %v: %v", group, err)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(out, "\ngroup %d/%d\n", i+1, len(duplicates))
+		decision, err := reviewGroup(in, out, plan)
+		if errors.Is(err, errReviewQuit) {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if err := c.decisions.save(decision); err != nil {
+			log.Errorf("save decision error: %v", err)
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprintf(out, "decisions saved to %s, run without --interactive to apply them\n", c.decisionsFile)
 	return nil
 }
 
@@ -195,11 +707,45 @@ func DeleteDuplicatesCmd() *cobra.Command {
 		RunE: impl.run,
 	}
 
-	cmd.Flags().StringVar(&impl.database, "database", "", "duplicate database json file")
+	cmd.Flags().StringVar(&impl.database, "database", "", "duplicate database file, groups of asset ids or file paths")
 	cobra.CheckErr(cmd.MarkFlagRequired("database"))
+	cmd.Flags().StringVar(&impl.format, "format", databaseFormatAuto,
+		"database format: auto|json|jsonl|csv|dupeguru|czkawka")
+	cmd.Flags().BoolVar(&impl.matchNames, "match-names", false,
+		"when several assets share the checksum of a database file, resolve it to the only one with the same file name")
+	cmd.Flags().StringVar(&impl.unresolvedFile, "unresolved", "", "write entries can't be resolved to assets to json file")
 	cmd.Flags().BoolVar(&impl.dryRun, "dry-run", false, "don't actually delete")
 	cmd.Flags().BoolVar(&impl.archive, "archive", false, "archive photo instead of delete")
 	cmd.Flags().IntVar(&impl.concurrent, "concurrent", 4, "num of concurrent workers")
 	cmd.Flags().BoolVar(&impl.force, "force", false, "force delete")
+	addSafetyFlags(cmd.Flags(), &impl.safety)
+	cmd.Flags().StringSlice(ViperKey_ScoreBy, []string{"size", "heic*10"},
+		"keeper score rules, `name[:weight]` adds criterion value, `name*factor` multiplies score when criterion holds. "+
+			"criteria: size|resolution|favorite|raw|heic|oldest|gps|live|device=<deviceId>")
+	cmd.Flags().StringSlice(ViperKey_TieBreakers, nil, "criteria to compare in order when scores are equal")
+	cobra.CheckErr(viper.BindPFlag(ViperKey_ScoreBy, cmd.Flags().Lookup(ViperKey_ScoreBy)))
+	cobra.CheckErr(viper.BindPFlag(ViperKey_TieBreakers, cmd.Flags().Lookup(ViperKey_TieBreakers)))
+	cmd.Flags().BoolVar(&impl.mergeMetadata, "merge-metadata", false,
+		"carry albums, tags, favorite, archive state and description of deleted duplicates over to the kept one")
+	cmd.MarkFlagsMutuallyExclusive("merge-metadata", "archive")
+	cmd.Flags().BoolVar(&impl.interactive, "interactive", false,
+		"review every group in terminal and save decisions to --decisions, nothing is deleted")
+	cmd.Flags().StringVar(&impl.decisionsFile, "decisions", "",
+		"reviewed decisions file, when set only reviewed groups are processed, deleting only reviewed members")
+	cmd.Flags().StringVar(&impl.reportFile, "report", "",
+		"write a self-contained html report of every group, combine with --dry-run to review before deleting")
+	cmd.Flags().BoolVar(&impl.resume, "resume", false,
+		"skip groups done by previous run and retry failed ones, progress is kept in <database>.state")
+	cmd.Flags().IntVar(&impl.batchSize, "batch-size", 1000,
+		"num of ids to collect across groups before one delete or archive request")
+	cmd.Flags().DurationVar(&impl.batchInterval, "batch-interval", 5*time.Second,
+		"flush collected ids at least this often")
+	cmd.Flags().StringVar(&impl.summaryFile, "summary", "", "write run summary as json to file, - for stdout")
+	cmd.Flags().BoolVar(&impl.ownerOnly, "owner-only", true,
+		"only keep or delete assets owned by current user, on by default, "+
+			"set --owner-only=false to consider assets of every user as before")
+	cmd.Flags().BoolVar(&impl.protectShared, "protect-shared", false,
+		"never delete assets in shared albums or shared links")
+	cmd.Flags().BoolVar(&impl.explain, "explain", false, "print score details of every group")
 	return cmd
 }
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_LivePhotos(t *testing.T) {
+	video1, video2 := uuid.NewString(), uuid.NewString()
+	still1 := &client.AssetResponseDto{Id: "still1", LivePhotoVideoId: &video1}
+	still2 := &client.AssetResponseDto{Id: "still2", LivePhotoVideoId: &video2}
+	still3 := &client.AssetResponseDto{Id: "still3", LivePhotoVideoId: &video1}
+	motion := &client.AssetResponseDto{Id: video1}
+	plain := &client.AssetResponseDto{Id: "plain"}
+
+	index := newLivePhotoIndex([]client.AssetResponseDto{*still1, *still2})
+	folded := foldLivePhotos([]assetQuality{{asset: motion}, {asset: still1}, {asset: plain}}, index)
+	require.Len(t, folded, 2)
+	require.Equal(t, "still1", folded[0].asset.Id)
+	require.Equal(t, "plain", folded[1].asset.Id)
+
+	// motion video whose still is outside the group is never a keeper nor a loser
+	orphan := &client.AssetResponseDto{Id: video2}
+	folded = foldLivePhotos([]assetQuality{{asset: orphan}, {asset: plain}}, index)
+	require.Len(t, folded, 1)
+	require.Equal(t, "plain", folded[0].asset.Id)
+
+	// video shared with keeper is never deleted
+	ids, err := lostMotionVideos(still1, []*client.AssetResponseDto{still2, still3, plain})
+	require.NoError(t, err)
+	require.Equal(t, []openapi_types.UUID{uuid.MustParse(video2)}, ids)
+
+	ids, err = lostMotionVideos(plain, []*client.AssetResponseDto{still1, still3})
+	require.NoError(t, err)
+	require.Equal(t, []openapi_types.UUID{uuid.MustParse(video1)}, ids)
+}
+
+func Test_ProcessGroupKeepsMotionVideoOfStillOutsideGroup(t *testing.T) {
+	video := uuid.NewString()
+	still := &client.AssetResponseDto{Id: uuid.NewString(), LivePhotoVideoId: &video, ExifInfo: exifSize(10)}
+	motion := &client.AssetResponseDto{Id: video, Type: client.AssetTypeEnumVIDEO, ExifInfo: exifSize(1)}
+	big := &client.AssetResponseDto{Id: uuid.NewString(), Type: client.AssetTypeEnumVIDEO, ExifInfo: exifSize(300)}
+	small := &client.AssetResponseDto{Id: uuid.NewString(), Type: client.AssetTypeEnumVIDEO, ExifInfo: exifSize(200)}
+
+	scorer, err := newAssetScorer([]string{"size"}, nil)
+	require.NoError(t, err)
+	c := &deleteDuplicatesCmd{client: newFakeClient(still, motion, big, small), scorer: scorer,
+		guard: &deleteGuard{}, dryRun: true}
+
+	// motion video is the smallest, but its still isn't in the group, so it's not deleted
+	item, err := c.processGroup(context.Background(), []string{motion.Id, big.Id, small.Id})
+	require.NoError(t, err)
+	require.Equal(t, []openapi_types.UUID{uuid.MustParse(small.Id)}, item.ids)
+}
+
+func Test_FinishGroupSummary(t *testing.T) {
+	video := uuid.NewString()
+	keeper := &client.AssetResponseDto{Id: uuid.NewString(), ExifInfo: exifSize(300)}
+	loser := &client.AssetResponseDto{Id: uuid.NewString(), LivePhotoVideoId: &video, ExifInfo: exifSize(200)}
+	motion := &client.AssetResponseDto{Id: video, Type: client.AssetTypeEnumVIDEO, ExifInfo: exifSize(50)}
+	scorer, err := newAssetScorer([]string{"size"}, nil)
+	require.NoError(t, err)
+
+	for _, dryRun := range []bool{true, false} {
+		c := &deleteDuplicatesCmd{client: newFakeClient(keeper, loser, motion), scorer: scorer,
+			guard: &deleteGuard{}, dryRun: dryRun}
+		item, err := c.processGroup(context.Background(), []string{keeper.Id, loser.Id})
+		require.NoError(t, err)
+		c.finishGroup(item, nil)
+
+		// motion video of the loser is counted, and dry run only counts what it would do
+		if dryRun {
+			require.Equal(t, runSummary{Processed: 1, Kept: 1, WouldDelete: 2, WouldReclaimBytes: 250}, c.summary)
+		} else {
+			require.Equal(t, runSummary{Processed: 1, Kept: 1, Deleted: 2, BytesReclaimed: 250}, c.summary)
+		}
+	}
+}
+
+func Test_ConfirmDeleteCountsMotionVideos(t *testing.T) {
+	defer viper.Set(ViperKey_MaxDelete, 0)
+	video := uuid.NewString()
+	still := &client.AssetResponseDto{Id: uuid.NewString(), LivePhotoVideoId: &video}
+	motion := &client.AssetResponseDto{Id: video, Type: client.AssetTypeEnumVIDEO}
+	other := &client.AssetResponseDto{Id: uuid.NewString()}
+	c := &deleteDuplicatesCmd{client: newFakeClient(still, motion, other), safety: safetyOptions{yes: true}}
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+
+	// still may be deleted with its motion video, motion video in group is not counted twice
+	duplicates := [][]string{{still.Id, motion.Id, other.Id}, {motion.Id, other.Id}}
+	viper.Set(ViperKey_MaxDelete, 1)
+	_, err := c.confirmDelete(cmd, duplicates)
+	require.ErrorContains(t, err, "max-delete 1")
+
+	viper.Set(ViperKey_MaxDelete, 2)
+	ok, err := c.confirmDelete(cmd, duplicates)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileDownloader streams original files
+type fileDownloader interface {
+	DownloadFile(ctx context.Context, id openapi_types.UUID, params *client.DownloadFileParams,
+		reqEditors ...client.RequestEditorFn) (*http.Response, error)
+}
+
+// downloadManifest is the file name of each asset downloaded into a dir, so names stay the same across runs
+const downloadManifest = ".immich_download.json"
+
+type downloadAssetCmd struct {
+	dir       string
+	overwrite bool
+	selector  *assetSelector
+
+	client client.ClientWithResponsesInterface
+	files  fileDownloader
+	names  map[string]string // asset id -> file name, of downloaded assets
+	owners map[string]string // file name -> asset id
+}
+
+// loadManifest reads names of assets downloaded into dir before
+func (c *downloadAssetCmd) loadManifest() error {
+	c.names, c.owners = make(map[string]string), make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(c.dir, downloadManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &c.names); err != nil {
+		return fmt.Errorf("decode download manifest error: %w", err)
+	}
+	for id, name := range c.names {
+		c.owners[name] = id
+	}
+	return nil
+}
+
+func (c *downloadAssetCmd) saveManifest() error {
+	data, err := json.MarshalIndent(c.names, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(c.dir, downloadManifest)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// fileName returns name of asset's original file in dir, which is the name it's downloaded as before.
+// a name taken by another asset or by a file not downloaded by us is suffixed by id
+func (c *downloadAssetCmd) fileName(asset *client.AssetResponseDto) (string, bool) {
+	if name, ok := c.names[asset.Id]; ok {
+		return name, true
+	}
+
+	name := filepath.Base(asset.OriginalPath)
+	_, taken := c.owners[name]
+	if _, err := os.Lstat(filepath.Join(c.dir, name)); taken || err == nil {
+		ext := filepath.Ext(name)
+		name = fmt.Sprintf("%s_%s%s", strings.TrimSuffix(name, ext), asset.Id, ext)
+	}
+	return name, false
+}
+
+// download writes original file of asset into dir, files downloaded before are skipped unless overwrite
+func (c *downloadAssetCmd) download(ctx context.Context, asset *client.AssetResponseDto) error {
+	name, downloaded := c.fileName(asset)
+	path := filepath.Join(c.dir, name)
+	if downloaded && !c.overwrite {
+		if _, err := os.Stat(path); err == nil {
+			log.Infof("skip %s, %s exists", asset.Id, path)
+			return nil
+		}
+	}
+
+	id, err := uuid.Parse(asset.Id)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.files.DownloadFile(ctx, id, &client.DownloadFileParams{})
+	if err != nil {
+		return fmt.Errorf("DownloadFile call error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newUnexpectedResponse(resp.StatusCode)
+	}
+
+	// write to a temp file first, so an interrupted download never leaves a partial file behind
+	tmp := path + ".tmp"
+	if err := writeFile(tmp, resp.Body); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	c.names[asset.Id], c.owners[name] = name, asset.Id
+	log.Debugf("download %s to %s", asset.Id, path)
+	return nil
+}
+
+// writeFile streams r into a new file of path
+func writeFile(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// assets returns assets of ids, or assets matching query
+func (c *downloadAssetCmd) assets(cmd *cobra.Command, args []string) ([]client.AssetResponseDto, error) {
+	ids, assets, err := c.selector.resolve(cmd, c.client, args)
+	if err != nil || c.selector.isQuery() {
+		return assets, err
+	}
+
+	for _, id := range ids {
+		resp, err := c.client.GetAssetByIdWithResponse(cmd.Context(), id, &client.GetAssetByIdParams{})
+		if err != nil {
+			return nil, fmt.Errorf("GetAssetById %s call error: %w", id, err)
+		}
+		if resp.JSON200 == nil {
+			return nil, fmt.Errorf("get asset %s error: %w", id, newUnexpectedResponse(resp.StatusCode()))
+		}
+		assets = append(assets, *resp.JSON200)
+	}
+
+	return assets, nil
+}
+
+func (c *downloadAssetCmd) run(cmd *cobra.Command, args []string) error {
+	cli := newAPIClient()
+	c.client, c.files = cli, cli
+	assets, err := c.assets(cmd, args)
+	if err != nil {
+		log.Errorf("resolve assets error: %v", err)
+		return err
+	}
+
+	if len(assets) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		log.Errorf("create dir %s error: %v", c.dir, err)
+		return err
+	}
+
+	if err := c.loadManifest(); err != nil {
+		log.Errorf("load download manifest error: %v", err)
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	failed := 0
+	for i := range assets {
+		if err := c.download(cmd.Context(), &assets[i]); err != nil {
+			log.Errorf("download %s error: %v", assets[i].Id, err)
+			failed++
+		}
+	}
+
+	if err := c.saveManifest(); err != nil {
+		log.Errorf("save download manifest error: %v", err)
+		return err
+	}
+
+	if err := newFailureExitError(failed, len(assets), "asset(s)"); err != nil {
+		return err
+	}
+
+	log.Infof("download %d asset(s) to %s", len(assets), c.dir)
+	return nil
+}
+
+func DownloadAssetCmd() *cobra.Command {
+	impl := &downloadAssetCmd{selector: newAssetSelector()}
+	cmd := &cobra.Command{
+		Use:   "download_asset",
+		Short: "download_asset [id1] [id2] ... , `-` reads ids from stdin, or query flags select assets",
+		RunE:  impl.run,
+	}
+
+	cmd.Flags().StringVar(&impl.dir, "dir", ".", "directory to download original files into")
+	cmd.Flags().BoolVar(&impl.overwrite, "overwrite", false, "download again files downloaded before instead of skipping them")
+	impl.selector.addFlags(cmd.Flags(), "download")
+	return cmd
+}
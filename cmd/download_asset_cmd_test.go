@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_DownloadAsset(t *testing.T) {
+	dir := t.TempDir()
+	cli := newFakeClient()
+	newCmd := func() *downloadAssetCmd {
+		c := &downloadAssetCmd{dir: dir, files: cli}
+		require.NoError(t, c.loadManifest())
+		return c
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	first := &client.AssetResponseDto{Id: uuid.NewString(), OriginalPath: "/upload/x/IMG_0001.jpg"}
+	second := &client.AssetResponseDto{Id: uuid.NewString(), OriginalPath: "/upload/y/IMG_0001.jpg"}
+	mine := &client.AssetResponseDto{Id: uuid.NewString(), OriginalPath: "/upload/z/notes.txt"}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine"), 0o644))
+
+	c := newCmd()
+	require.NoError(t, c.download(context.Background(), first))
+	require.NoError(t, c.download(context.Background(), mine))
+	require.NoError(t, c.saveManifest())
+	require.Equal(t, first.Id, read("IMG_0001.jpg"))
+	require.Equal(t, "mine", read("notes.txt"), "files not downloaded by us are kept")
+	require.Equal(t, mine.Id, read("notes_"+mine.Id+".txt"))
+
+	// another run, in another order, keeps names of assets, and skips those downloaded
+	c = newCmd()
+	require.NoError(t, c.download(context.Background(), second))
+	require.NoError(t, c.download(context.Background(), first))
+	require.Equal(t, second.Id, read("IMG_0001_"+second.Id+".jpg"))
+	require.Equal(t, first.Id, read("IMG_0001.jpg"))
+	require.Equal(t, 3, cli.downloads)
+
+	c.overwrite = true
+	require.NoError(t, c.download(context.Background(), first))
+	require.Equal(t, 4, cli.downloads)
+	require.Equal(t, first.Id, read("IMG_0001.jpg"))
+
+	// broken download leaves neither file nor temp file behind
+	cli.broken = true
+	third := &client.AssetResponseDto{Id: uuid.NewString(), OriginalPath: "/upload/z/IMG_0003.jpg"}
+	require.Error(t, c.download(context.Background(), third))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 5, "no temp file left")
+}
@@ -0,0 +1,376 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	databaseFormatAuto     = "auto"
+	databaseFormatJSON     = "json"
+	databaseFormatJSONL    = "jsonl"
+	databaseFormatCSV      = "csv"
+	databaseFormatDupeGuru = "dupeguru"
+	databaseFormatCzkawka  = "czkawka"
+)
+
+// readDuplicates reads groups of asset ids or file paths from database file in format
+func readDuplicates(path string, format string) ([][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == databaseFormatAuto {
+		if format, err = detectDatabaseFormat(path, data); err != nil {
+			return nil, err
+		}
+		log.Debugf("database `%s` format: %s", path, format)
+	}
+
+	switch format {
+	case databaseFormatJSON:
+		var duplicates [][]string
+		if err := json.Unmarshal(data, &duplicates); err != nil {
+			return nil, err
+		}
+		return duplicates, nil
+	case databaseFormatJSONL:
+		return readJSONLDuplicates(data)
+	case databaseFormatCSV:
+		return readCSVDuplicates(data)
+	case databaseFormatDupeGuru:
+		return readDupeGuruDuplicates(data)
+	case databaseFormatCzkawka:
+		return readCzkawkaDuplicates(data)
+	default:
+		return nil, fmt.Errorf("unknown database format `%s`", format)
+	}
+}
+
+// detectDatabaseFormat guesses format by extension and content, json which is neither groups of strings nor
+// has any czkawka group is an error, rather than an empty database
+func detectDatabaseFormat(path string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl":
+		return databaseFormatJSONL, nil
+	case ".csv":
+		firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+		if bytes.Contains(firstLine, []byte("Group ID")) {
+			return databaseFormatDupeGuru, nil
+		}
+		return databaseFormatCSV, nil
+	}
+
+	var duplicates [][]string
+	if json.Unmarshal(data, &duplicates) == nil {
+		return databaseFormatJSON, nil
+	}
+
+	if groups, err := readCzkawkaDuplicates(data); err == nil && len(groups) > 0 {
+		return databaseFormatCzkawka, nil
+	}
+
+	return "", fmt.Errorf("unrecognized database format of `%s`, set --format", path)
+}
+
+func readJSONLDuplicates(data []byte) ([][]string, error) {
+	var duplicates [][]string
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var group []string
+		if err := decoder.Decode(&group); errors.Is(err, io.EOF) {
+			return duplicates, nil
+		} else if err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, group)
+	}
+}
+
+// readCSVDuplicates reads one group per record
+func readCSVDuplicates(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	var duplicates [][]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return duplicates, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		var group []string
+		for _, field := range record {
+			if field = strings.TrimSpace(field); field != "" {
+				group = append(group, field)
+			}
+		}
+		duplicates = append(duplicates, group)
+	}
+}
+
+// readDupeGuruDuplicates reads csv exported by dupeGuru, which has `Group ID`, `Filename` and `Folder` columns
+func readDupeGuruDuplicates(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"Group ID", "Filename", "Folder"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("dupeGuru csv has no `%s` column", name)
+		}
+	}
+
+	var groupIds []string
+	groups := make(map[string][]string)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("malform dupeGuru record: %v", record)
+		}
+
+		groupId := record[columns["Group ID"]]
+		if _, ok := groups[groupId]; !ok {
+			groupIds = append(groupIds, groupId)
+		}
+		groups[groupId] = append(groups[groupId],
+			filepath.Join(record[columns["Folder"]], record[columns["Filename"]]))
+	}
+
+	var duplicates [][]string
+	for _, groupId := range groupIds {
+		duplicates = append(duplicates, groups[groupId])
+	}
+
+	return duplicates, nil
+}
+
+// readCzkawkaDuplicates reads json exported by czkawka, every array of objects with `path` is a group.
+// it's either a list of groups, or groups keyed by file size, which are read in order of keys
+func readCzkawkaDuplicates(data []byte) ([][]string, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	var duplicates [][]string
+	var walk func(node any)
+	walk = func(node any) {
+		switch v := node.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				return lessCzkawkaKey(keys[i], keys[j])
+			})
+			for _, key := range keys {
+				walk(v[key])
+			}
+		case []any:
+			var group []string
+			for _, child := range v {
+				if entry, ok := child.(map[string]any); ok {
+					if path, ok := entry["path"].(string); ok {
+						group = append(group, path)
+						continue
+					}
+				}
+				walk(child)
+			}
+			if len(group) > 0 {
+				duplicates = append(duplicates, group)
+			}
+		}
+	}
+	walk(root)
+	return duplicates, nil
+}
+
+// lessCzkawkaKey compares numeric keys like file size by value, others as strings
+func lessCzkawkaKey(a, b string) bool {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// unresolvedEntry is a database entry that can't be resolved to an asset id
+type unresolvedEntry struct {
+	Group  int    `json:"group"`
+	Entry  string `json:"entry"`
+	Reason string `json:"reason"`
+}
+
+// indexedAsset is what assetIndex needs of an asset
+type indexedAsset struct {
+	id   string
+	name string
+}
+
+// assetIndex resolves file paths to asset ids
+type assetIndex struct {
+	byPath     map[string]string
+	byChecksum map[string][]indexedAsset
+	matchNames bool
+}
+
+// newAssetIndex indexes assets not trashed, matchNames picks one of assets sharing checksum of local file by name
+func newAssetIndex(assets []client.AssetResponseDto, matchNames bool) *assetIndex {
+	index := &assetIndex{
+		byPath:     make(map[string]string),
+		byChecksum: make(map[string][]indexedAsset),
+		matchNames: matchNames,
+	}
+	for _, asset := range assets {
+		if asset.IsTrashed {
+			continue
+		}
+		index.byPath[asset.OriginalPath] = asset.Id
+		index.byChecksum[asset.Checksum] = append(index.byChecksum[asset.Checksum],
+			indexedAsset{id: asset.Id, name: asset.OriginalFileName})
+	}
+
+	return index
+}
+
+// fileChecksum returns base64 encoded sha1 of file, the same as AssetResponseDto.Checksum
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, bufio.NewReader(file)); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// resolve finds asset id of path by original path, then checksum of local file. if several assets share the
+// checksum, and matchNames is set, the only one of them with the same file name is taken
+func (i *assetIndex) resolve(path string) (string, error) {
+	if id, ok := i.byPath[path]; ok {
+		return id, nil
+	}
+
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return "", fmt.Errorf("no asset matches path, checksum local file error: %w", err)
+	}
+
+	candidates := i.byChecksum[checksum]
+	switch {
+	case len(candidates) == 1:
+		return candidates[0].id, nil
+	case len(candidates) == 0:
+		return "", errors.New("no asset matches path or checksum")
+	case !i.matchNames:
+		return "", fmt.Errorf("%d assets match checksum, set --match-names to pick one by file name", len(candidates))
+	}
+
+	// original file name may be stored without extension
+	name := filepath.Base(path)
+	var ids []string
+	for _, candidate := range candidates {
+		if candidate.name == name || candidate.name == strings.TrimSuffix(name, filepath.Ext(name)) {
+			ids = append(ids, candidate.id)
+		}
+	}
+
+	if len(ids) != 1 {
+		return "", fmt.Errorf("%d assets match checksum, %d of them match name `%s`", len(candidates), len(ids), name)
+	}
+	return ids[0], nil
+}
+
+// resolveDuplicates replaces file paths in duplicates with asset ids, unresolved entries are dropped and reported
+func resolveDuplicates(ctx context.Context, cli client.ClientWithResponsesInterface,
+	duplicates [][]string, matchNames bool) ([][]string, []unresolvedEntry, error) {
+	hasPath := false
+	for _, group := range duplicates {
+		for _, entry := range group {
+			if _, err := uuid.Parse(entry); err != nil {
+				hasPath = true
+			}
+		}
+	}
+
+	if !hasPath {
+		return duplicates, nil, nil
+	}
+
+	var assets []client.AssetResponseDto
+	err := listAssets(ctx, cli, client.GetAllAssetsParams{}, func(page []client.AssetResponseDto) error {
+		assets = append(assets, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := newAssetIndex(assets, matchNames)
+	var resolved [][]string
+	var unresolved []unresolvedEntry
+	for i, group := range duplicates {
+		var ids []string
+		seen := make(map[string]bool)
+		for _, entry := range group {
+			id := entry
+			if _, err := uuid.Parse(entry); err != nil {
+				if id, err = index.resolve(entry); err != nil {
+					unresolved = append(unresolved, unresolvedEntry{Group: i, Entry: entry, Reason: err.Error()})
+					continue
+				}
+			}
+
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) > 1 {
+			resolved = append(resolved, ids)
+		}
+	}
+
+	return resolved, unresolved, nil
+}
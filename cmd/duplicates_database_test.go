@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ReadDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		expect  [][]string
+	}{
+		{"db.json", `[["a","b"],["c","d"]]`, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"db.jsonl", "[\"a\",\"b\"]\n[\"c\",\"d\"]\n", [][]string{{"a", "b"}, {"c", "d"}}},
+		{"db.csv", "a,b\nc,d,\n", [][]string{{"a", "b"}, {"c", "d"}}},
+		{"dupeguru.csv", "Group ID,Filename,Folder,Size (KB),Kind,Match %\n" +
+			"0,a.jpg,/p,1,jpg,100\n0,b.jpg,/q,1,jpg,100\n1,c.jpg,/p,1,jpg,100\n1,c.jpg,/q,1,jpg,100\n",
+			[][]string{{"/p/a.jpg", "/q/b.jpg"}, {"/p/c.jpg", "/q/c.jpg"}}},
+		{"czkawka.json", `[[{"path":"/p/a.jpg","size":1},{"path":"/q/a.jpg","size":1}]]`,
+			[][]string{{"/p/a.jpg", "/q/a.jpg"}}},
+		{"czkawka_size.json", `{"1024":[[{"path":"/p/a.jpg"},{"path":"/q/a.jpg"}]]}`,
+			[][]string{{"/p/a.jpg", "/q/a.jpg"}}},
+		{"czkawka_sizes.json", `{"2048":[[{"path":"/p/b.jpg"},{"path":"/q/b.jpg"}]],` +
+			`"512":[[{"path":"/p/a.jpg"},{"path":"/q/a.jpg"}]]}`,
+			[][]string{{"/p/a.jpg", "/q/a.jpg"}, {"/p/b.jpg", "/q/b.jpg"}}},
+	}
+
+	for _, tt := range tests {
+		duplicates, err := readDuplicates(write(tt.name, tt.content), databaseFormatAuto)
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.expect, duplicates, tt.name)
+	}
+
+	// json of unknown shape is not taken as an empty czkawka database
+	for _, content := range []string{`{"groups":1}`, `{}`, `[1,2]`} {
+		_, err := readDuplicates(write("unknown.json", content), databaseFormatAuto)
+		require.Error(t, err, content)
+	}
+}
+
+func Test_AssetIndexResolve(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) (string, string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		checksum, err := fileChecksum(path)
+		require.NoError(t, err)
+		return path, checksum
+	}
+	unique, uniqueChecksum := write("unique.jpg", "unique")
+	shared, sharedChecksum := write("IMG_0001.jpg", "shared")
+	renamed, _ := write("b.jpg", "other content")
+
+	assets := []client.AssetResponseDto{
+		{Id: "1", OriginalPath: "/upload/a.jpg", OriginalFileName: "a"},
+		{Id: "2", OriginalPath: "/upload/b.jpg", OriginalFileName: "b", Checksum: uniqueChecksum},
+		{Id: "3", OriginalPath: "/upload/x/IMG_0001.jpg", OriginalFileName: "IMG_0001", Checksum: sharedChecksum},
+		{Id: "4", OriginalPath: "/upload/y/IMG_0002.jpg", OriginalFileName: "IMG_0002", Checksum: sharedChecksum},
+	}
+
+	for _, matchNames := range []bool{false, true} {
+		index := newAssetIndex(assets, matchNames)
+		for path, expect := range map[string]string{"/upload/a.jpg": "1", unique: "2"} {
+			id, err := index.resolve(path)
+			require.NoError(t, err, path)
+			require.Equal(t, expect, id, path)
+		}
+
+		// name alone never resolves, checksum must agree
+		_, err := index.resolve("/backup/a.jpg")
+		require.Error(t, err)
+		_, err = index.resolve(renamed)
+		require.Error(t, err)
+	}
+
+	// assets sharing checksum are told apart by name only when asked
+	_, err := newAssetIndex(assets, false).resolve(shared)
+	require.Error(t, err)
+	id, err := newAssetIndex(assets, true).resolve(shared)
+	require.NoError(t, err)
+	require.Equal(t, "3", id)
+}
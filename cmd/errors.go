@@ -2,6 +2,42 @@ package cmd
 
 import "fmt"
 
+const (
+	ExitCodePartialFailure = 2
+	ExitCodeTotalFailure   = 3
+	ExitCodeInterrupted    = 130
+)
+
 func newUnexpectedResponse(code int) error {
 	return fmt.Errorf("unexpected response: %v", code)
 }
+
+// ExitError is an error which asks process to exit with Code
+type ExitError struct {
+	Code int
+	err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.err
+}
+
+func newExitError(code int, format string, a ...any) error {
+	return &ExitError{Code: code, err: fmt.Errorf(format, a...)}
+}
+
+// newFailureExitError returns nil if nothing failed, otherwise an exit error of total or partial failure
+func newFailureExitError(failed int, total int, what string) error {
+	switch {
+	case failed == 0:
+		return nil
+	case failed == total:
+		return newExitError(ExitCodeTotalFailure, "all %d %s failed", failed, what)
+	default:
+		return newExitError(ExitCodePartialFailure, "%d of %d %s failed", failed, total, what)
+	}
+}
@@ -0,0 +1,533 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// assetFilter is a compiled `--where` expression, e.g.
+//
+//	type == "VIDEO" && exif.make == "Apple" && exif.fileSizeInByte > 50MB && !isTrashed
+//
+// fields are json names of AssetResponseDto, nested by `.`, and `exif` is short for `exifInfo`, unknown fields are
+// an error.
+// operators are == != < <= > >= =~ (regexp) && || ! and parentheses. numbers may have KB, MB, GB or TB
+// suffix (1024 based). strings that both look like dates (RFC3339 or 2006-01-02) are compared as time
+type assetFilter struct {
+	expr filterExpr
+}
+
+// filterExpr evaluates to a json value: nil, bool, float64, string, []any or map[string]any
+type filterExpr interface {
+	eval(env map[string]any) (any, error)
+}
+
+// parseFilter compiles expression, empty expression matches every asset
+func parseFilter(expr string) (*assetFilter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected `%s` at %d", tok.text, tok.pos)
+	}
+
+	return &assetFilter{expr: e}, nil
+}
+
+// match reports whether asset satisfies filter, nil filter matches everything
+func (f *assetFilter) match(asset any) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+
+	data, err := json.Marshal(asset)
+	if err != nil {
+		return false, err
+	}
+
+	var env map[string]any
+	if err := json.Unmarshal(data, &env); err != nil {
+		return false, err
+	}
+
+	v, err := f.expr.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int
+	num  float64
+	str  string
+}
+
+var sizeUnits = map[string]float64{
+	"":   1,
+	"B":  1,
+	"KB": 1 << 10,
+	"MB": 1 << 20,
+	"GB": 1 << 30,
+	"TB": 1 << 40,
+}
+
+// operators, longer ones first
+var filterOperators = []string{"==", "!=", "<=", ">=", "=~", "&&", "||", "<", ">", "!"}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			var sb strings.Builder
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: string(runes[i : j+1]), pos: i, str: sb.String()})
+			i = j + 1
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			k := j
+			for k < len(runes) && unicode.IsLetter(runes[k]) {
+				k++
+			}
+			num, err := strconv.ParseFloat(string(runes[i:j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("malform number `%s` at %d", string(runes[i:k]), i)
+			}
+			unit, ok := sizeUnits[strings.ToUpper(string(runes[j:k]))]
+			if !ok {
+				return nil, fmt.Errorf("unknown unit `%s` at %d", string(runes[j:k]), j)
+			}
+			tokens = append(tokens, filterToken{kind: tokenNumber, text: string(runes[i:k]), pos: i, num: num * unit})
+			i = k
+		case isIdentRune(r):
+			j := i
+			for j < len(runes) && isIdentRune(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, filterToken{kind: tokenIdent, text: string(runes[i:j]), pos: i})
+			i = j
+		default:
+			matched := false
+			for _, op := range filterOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, filterToken{kind: tokenOp, text: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected `%c` at %d", r, i)
+			}
+		}
+	}
+
+	return append(tokens, filterToken{kind: tokenEOF, text: "end of expression", pos: len(runes)}), nil
+}
+
+// filterParser is a recursive descent parser, precedence from low to high: || && ! comparison
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) acceptOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "||", left: left, right: right}
+	}
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if _, ok := p.acceptOp("!"); ok {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{e: e}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterExpr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.acceptOp("==", "!=", "<=", ">=", "<", ">", "=~")
+	if !ok {
+		return left, nil
+	}
+
+	if op == "=~" {
+		tok := p.next()
+		if tok.kind != tokenString {
+			return nil, fmt.Errorf("=~ expects a string pattern at %d", tok.pos)
+		}
+		re, err := regexp.Compile(tok.str)
+		if err != nil {
+			return nil, fmt.Errorf("malform pattern at %d: %w", tok.pos, err)
+		}
+		return &matchExpr{e: left, re: re}, nil
+	}
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &compareExpr{op: op, left: left, right: right}, nil
+}
+
+func (p *filterParser) parsePrimary() (filterExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expect `)` at %d, got `%s`", closing.pos, closing.text)
+		}
+		return e, nil
+	case tokenString:
+		return literalExpr{v: tok.str}, nil
+	case tokenNumber:
+		return literalExpr{v: tok.num}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return literalExpr{v: true}, nil
+		case "false":
+			return literalExpr{v: false}, nil
+		case "null":
+			return literalExpr{v: nil}, nil
+		}
+		path := strings.Split(tok.text, ".")
+		if path[0] == "exif" {
+			path[0] = "exifInfo"
+		}
+		if !isAssetField(path) {
+			return nil, fmt.Errorf("unknown field `%s` at %d", tok.text, tok.pos)
+		}
+		return fieldExpr{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unexpected `%s` at %d", tok.text, tok.pos)
+	}
+}
+
+type literalExpr struct {
+	v any
+}
+
+func (e literalExpr) eval(map[string]any) (any, error) {
+	return e.v, nil
+}
+
+// isAssetField reports whether path is json names of AssetResponseDto fields, nested in struct fields.
+// a typo would otherwise be null and match, say, every asset by `!isTrashd`
+func isAssetField(path []string) bool {
+	t := reflect.TypeOf(client.AssetResponseDto{})
+	for _, name := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName == name {
+				t, found = field.Type, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldExpr is a json path, missing field is null
+type fieldExpr struct {
+	path []string
+}
+
+func (e fieldExpr) eval(env map[string]any) (any, error) {
+	var v any = env
+	for _, name := range e.path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		v = m[name]
+	}
+	return v, nil
+}
+
+type notExpr struct {
+	e filterExpr
+}
+
+func (e *notExpr) eval(env map[string]any) (any, error) {
+	v, err := e.e.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+type logicalExpr struct {
+	op          string
+	left, right filterExpr
+}
+
+func (e *logicalExpr) eval(env map[string]any) (any, error) {
+	left, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(left) == (e.op == "||") {
+		return e.op == "||", nil
+	}
+
+	right, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+type matchExpr struct {
+	e  filterExpr
+	re *regexp.Regexp
+}
+
+func (e *matchExpr) eval(env map[string]any) (any, error) {
+	v, err := e.e.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(string)
+	return ok && e.re.MatchString(s), nil
+}
+
+type compareExpr struct {
+	op          string
+	left, right filterExpr
+}
+
+func (e *compareExpr) eval(env map[string]any) (any, error) {
+	left, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "==" || e.op == "!=" {
+		equal := compareValues(left, right) == 0
+		return equal == (e.op == "=="), nil
+	}
+
+	// ordering with null or mismatched types is false
+	c := compareValues(left, right)
+	if c == incomparable {
+		return false, nil
+	}
+	switch e.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+const incomparable = 2
+
+var filterTimeLayouts = []string{time.RFC3339Nano, time.DateOnly}
+
+func parseFilterTime(s string) (time.Time, bool) {
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareValues returns -1, 0, 1, or incomparable
+func compareValues(a, b any) int {
+	switch x := a.(type) {
+	case nil:
+		if b == nil {
+			return 0
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0
+			}
+			return incomparable
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			tx, okx := parseFilterTime(x)
+			ty, oky := parseFilterTime(y)
+			if okx && oky {
+				return tx.Compare(ty)
+			}
+			return strings.Compare(x, y)
+		}
+	}
+	return incomparable
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []any:
+		return len(x) > 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_AssetFilter(t *testing.T) {
+	cameraMake, size := "Apple", int64(60<<20)
+	asset := client.AssetResponseDto{
+		Id:               "a",
+		Type:             client.AssetTypeEnumVIDEO,
+		OriginalFileName: "IMG_0001",
+		FileCreatedAt:    time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		ExifInfo:         &client.ExifResponseDto{Make: &cameraMake, FileSizeInByte: &size},
+	}
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{``, true},
+		{`type == "VIDEO" && exif.make == "Apple" && exif.fileSizeInByte > 50MB && !isTrashed`, true},
+		{`type == 'IMAGE' || exifInfo.fileSizeInByte <= 50mb`, false},
+		{`!(isFavorite || isArchived)`, true},
+		{`originalFileName =~ "^IMG_\\d+$"`, true},
+		{`exif.model == null && exif.model != "x"`, true},
+		{`exif.model > 1`, false},
+		{`fileCreatedAt >= "2023-01-01" && fileCreatedAt < "2023-12-31T00:00:00Z"`, true},
+		{`isTrashed == false && livePhotoVideoId`, false},
+	}
+	for _, tt := range tests {
+		filter, err := parseFilter(tt.expr)
+		require.NoError(t, err, tt.expr)
+		ok, err := filter.match(asset)
+		require.NoError(t, err, tt.expr)
+		require.Equal(t, tt.match, ok, tt.expr)
+	}
+
+	for _, expr := range []string{`type ==`, `(isTrashed`, `size > 1XB`, `name =~ 1`, `"abc`, `a # b`, `a b`,
+		`!isTrashd`, `exif.modle == "x"`, `exif.make.x`, `fileCreatedAt.year`} {
+		_, err := parseFilter(expr)
+		require.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	findModeChecksum = "checksum"
+	findModeNameSize = "name-size"
+	findModePHash    = "phash"
+	findModeBurst    = "burst"
+)
+
+type findDuplicatesCmd struct {
+	database   string
+	mode       string
+	distance   int
+	hashCache  string
+	concurrent int
+	window     time.Duration
+	where      string
+
+	paramsFlagSet *pflag.FlagSet
+	client        client.ClientWithResponsesInterface
+}
+
+// assetKeyFunc returns the grouping key of asset, ok=false means asset should not be grouped
+type assetKeyFunc func(asset *client.AssetResponseDto) (key string, ok bool)
+
+func checksumKey(asset *client.AssetResponseDto) (string, bool) {
+	return asset.Checksum, asset.Checksum != ""
+}
+
+func nameSizeKey(asset *client.AssetResponseDto) (string, bool) {
+	if asset.ExifInfo == nil || asset.ExifInfo.FileSizeInByte == nil {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s|%d", asset.OriginalFileName, *asset.ExifInfo.FileSizeInByte), true
+}
+
+// groupAssets groups assets by key, only groups with more than 1 asset are returned
+// groups are ordered by the first appearance of their key
+func groupAssets(assets []client.AssetResponseDto, keyFn assetKeyFunc) [][]string {
+	var keys []string
+	groups := make(map[string][]string)
+	for i := range assets {
+		asset := &assets[i]
+		if asset.IsTrashed {
+			continue
+		}
+
+		key, ok := keyFn(asset)
+		if !ok {
+			continue
+		}
+
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], asset.Id)
+	}
+
+	duplicates := [][]string{}
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			duplicates = append(duplicates, groups[key])
+		}
+	}
+
+	return duplicates
+}
+
+// groupFunc returns how assets are grouped in current mode
+func (c *findDuplicatesCmd) groupFunc() (func(ctx context.Context, assets []client.AssetResponseDto) ([][]string, error), error) {
+	byKey := func(keyFn assetKeyFunc) func(context.Context, []client.AssetResponseDto) ([][]string, error) {
+		return func(_ context.Context, assets []client.AssetResponseDto) ([][]string, error) {
+			return groupAssets(assets, keyFn), nil
+		}
+	}
+
+	switch c.mode {
+	case findModeChecksum:
+		return byKey(checksumKey), nil
+	case findModeNameSize:
+		return byKey(nameSizeKey), nil
+	case findModePHash:
+		return c.groupByPerceptualHash, nil
+	case findModeBurst:
+		return func(_ context.Context, assets []client.AssetResponseDto) ([][]string, error) {
+			return groupBursts(assets, c.window), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown mode `%s`", c.mode)
+	}
+}
+
+// burstTime returns when asset was taken, prefer exif time
+func burstTime(asset *client.AssetResponseDto) time.Time {
+	if asset.ExifInfo != nil && asset.ExifInfo.DateTimeOriginal != nil {
+		return *asset.ExifInfo.DateTimeOriginal
+	}
+	return asset.LocalDateTime
+}
+
+// groupBursts groups images of the same device and dimensions, taken within window after the previous one
+func groupBursts(assets []client.AssetResponseDto, window time.Duration) [][]string {
+	var images []*client.AssetResponseDto
+	for i := range assets {
+		if assets[i].Type == client.AssetTypeEnumIMAGE && !assets[i].IsTrashed && assets[i].DeviceId != "" {
+			images = append(images, &assets[i])
+		}
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		return burstTime(images[i]).Before(burstTime(images[j]))
+	})
+
+	key := func(asset *client.AssetResponseDto) string {
+		var dimensions string
+		if exif := asset.ExifInfo; exif != nil && exif.ExifImageWidth != nil && exif.ExifImageHeight != nil {
+			dimensions = fmt.Sprintf("%gx%g", *exif.ExifImageWidth, *exif.ExifImageHeight)
+		}
+		return asset.DeviceId + "|" + dimensions
+	}
+
+	// current burst of each key
+	bursts := make(map[string][]*client.AssetResponseDto)
+	duplicates := [][]string{}
+	closeBurst := func(burst []*client.AssetResponseDto) {
+		if len(burst) > 1 {
+			var ids []string
+			for _, asset := range burst {
+				ids = append(ids, asset.Id)
+			}
+			duplicates = append(duplicates, ids)
+		}
+	}
+
+	for _, image := range images {
+		k := key(image)
+		burst := bursts[k]
+		if len(burst) > 0 && burstTime(image).Sub(burstTime(burst[len(burst)-1])) > window {
+			closeBurst(burst)
+			burst = nil
+		}
+		bursts[k] = append(burst, image)
+	}
+
+	var keys []string
+	for k := range bursts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return burstTime(bursts[keys[i]][0]).Before(burstTime(bursts[keys[j]][0]))
+	})
+	for _, k := range keys {
+		closeBurst(bursts[k])
+	}
+
+	return duplicates
+}
+
+func (c *findDuplicatesCmd) groupByPerceptualHash(ctx context.Context,
+	assets []client.AssetResponseDto) ([][]string, error) {
+	cachePath := c.hashCache
+	if cachePath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		cachePath = filepath.Join(home, ".immich_phash.json")
+	}
+
+	cache, err := loadHashCache(cachePath)
+	if err != nil {
+		log.Errorf("load hash cache error: %v", err)
+		return nil, err
+	}
+
+	var images []client.AssetResponseDto
+	for _, asset := range assets {
+		if asset.Type == client.AssetTypeEnumIMAGE && !asset.IsTrashed {
+			images = append(images, asset)
+		}
+	}
+
+	log.Infof("hashing %d images...", len(images))
+	ids, hashes := hashAssets(ctx, c.client, cache, images, c.concurrent)
+	if err := cache.save(); err != nil {
+		log.Warnf("save hash cache `%s` error: %v", cachePath, err)
+	}
+
+	return groupByHash(ids, hashes, c.distance), nil
+}
+
+func (c *findDuplicatesCmd) run(cmd *cobra.Command, _ []string) error {
+	if c.concurrent < 1 {
+		return fmt.Errorf("--concurrent must be at least 1, got %d", c.concurrent)
+	}
+
+	group, err := c.groupFunc()
+	if err != nil {
+		return err
+	}
+
+	var params client.GetAllAssetsParams
+	if err := setFormFields(&params, c.paramsFlagSet); err != nil {
+		return err
+	}
+
+	filter, err := parseFilter(c.where)
+	if err != nil {
+		log.Errorf("parse where error: %v", err)
+		return err
+	}
+
+	c.client = newClient()
+	var assets []client.AssetResponseDto
+	err = listAssets(cmd.Context(), c.client, params, func(page []client.AssetResponseDto) error {
+		for _, asset := range page {
+			if ok, err := filter.match(asset); err != nil {
+				return err
+			} else if ok {
+				assets = append(assets, asset)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("list assets error: %v", err)
+		return err
+	}
+
+	duplicates, err := group(cmd.Context(), assets)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("found %d duplicate group(s) in %d assets", len(duplicates), len(assets))
+	return writeDuplicates(c.database, duplicates)
+}
+
+// writeDuplicates writes groups in the format `delete_duplicates --database` reads
+func writeDuplicates(path string, duplicates [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		log.Errorf("create `%s` error: %v", path, err)
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(duplicates); err != nil {
+		log.Errorf("encode duplicates error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func FindDuplicatesCmd() *cobra.Command {
+	impl := &findDuplicatesCmd{}
+	impl.paramsFlagSet = pflag.NewFlagSet("", pflag.ContinueOnError)
+	addFlagSetByFormFields(&client.GetAllAssetsParams{}, impl.paramsFlagSet)
+
+	cmd := &cobra.Command{
+		Use:   "find_duplicates",
+		Short: "find duplicate assets and write them as delete_duplicates database",
+		RunE:  impl.run,
+	}
+
+	cmd.Flags().StringVar(&impl.database, "database", "", "output duplicate database json file")
+	cobra.CheckErr(cmd.MarkFlagRequired("database"))
+	cmd.Flags().StringVar(&impl.mode, "mode", findModeChecksum, "group assets by: checksum|name-size|phash|burst")
+	cmd.Flags().IntVar(&impl.distance, "distance", 4, "max hamming distance between perceptual hashes of any two assets of a group in phash mode")
+	cmd.Flags().StringVar(&impl.hashCache, "hash-cache", "", "perceptual hash cache file (default is $HOME/.immich_phash.json)")
+	cmd.Flags().IntVar(&impl.concurrent, "concurrent", 4, "num of concurrent thumbnail downloads in phash mode")
+	cmd.Flags().DurationVar(&impl.window, "window", 2*time.Second,
+		"max interval between shots of the same device and dimensions in burst mode")
+	cmd.Flags().StringVar(&impl.where, "where", "", "only find duplicates among assets matching this filter expression")
+	cmd.Flags().AddFlagSet(impl.paramsFlagSet)
+	return cmd
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_GroupAssets(t *testing.T) {
+	assets := []client.AssetResponseDto{
+		{Id: "a", Checksum: "c1", OriginalFileName: "x.jpg", ExifInfo: exifSize(10)},
+		{Id: "b", Checksum: "c2", OriginalFileName: "x.jpg", ExifInfo: exifSize(10)},
+		{Id: "c", Checksum: "c1", OriginalFileName: "y.jpg", ExifInfo: exifSize(20)},
+		{Id: "d", Checksum: "c1", IsTrashed: true},
+		{Id: "e", Checksum: "c3", OriginalFileName: "x.jpg"},
+	}
+
+	require.Equal(t, [][]string{{"a", "c"}}, groupAssets(assets, checksumKey))
+	require.Equal(t, [][]string{{"a", "b"}}, groupAssets(assets, nameSizeKey))
+	require.Equal(t, [][]string{}, groupAssets(assets[:1], checksumKey))
+}
+
+func Test_GroupBursts(t *testing.T) {
+	base := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	image := func(id, device string, offset time.Duration, width float32) client.AssetResponseDto {
+		height := float32(100)
+		taken := base.Add(offset)
+		return client.AssetResponseDto{Id: id, DeviceId: device, Type: client.AssetTypeEnumIMAGE,
+			LocalDateTime: base, ExifInfo: &client.ExifResponseDto{
+				DateTimeOriginal: &taken, ExifImageWidth: &width, ExifImageHeight: &height}}
+	}
+
+	assets := []client.AssetResponseDto{
+		image("a", "phone", 0, 100),
+		image("c", "phone", 1200*time.Millisecond, 100),
+		image("b", "phone", 500*time.Millisecond, 100),
+		image("d", "phone", 5*time.Second, 100),
+		image("e", "phone", 5500*time.Millisecond, 200),
+		image("f", "camera", 300*time.Millisecond, 100),
+		image("g", "phone", 6*time.Second, 100),
+	}
+
+	require.Equal(t, [][]string{{"a", "b", "c"}, {"d", "g"}}, groupBursts(assets, time.Second))
+	require.Equal(t, [][]string{}, groupBursts(assets, 100*time.Millisecond))
+}
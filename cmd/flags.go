@@ -2,6 +2,7 @@ package cmd
 
 import (
 	openapi_types "github.com/oapi-codegen/runtime/types"
+	"strings"
 	"time"
 )
 
@@ -22,7 +23,7 @@ type Time struct {
 }
 
 func (t *Time) Set(s string) error {
-	tm, err := time.Parse(time.RFC3339, s)
+	tm, err := parseTimeValue(s)
 	if err != nil {
 		return err
 	}
@@ -36,8 +37,10 @@ func (t *Time) Type() string {
 }
 
 type genericVar struct {
-	s string
-	t string
+	s      string
+	t      string
+	slice  bool     // values accumulate over repeated flags, each may be separated by comma
+	values []string // values of slice
 }
 
 func (t *genericVar) String() string {
@@ -45,7 +48,17 @@ func (t *genericVar) String() string {
 }
 
 func (t *genericVar) Set(s string) error {
-	t.s = s
+	if !t.slice {
+		t.s = s
+		return nil
+	}
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			t.values = append(t.values, v)
+		}
+	}
+	t.s = strings.Join(t.values, ",")
 	return nil
 }
 
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"github.com/chain710/immich-cli/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const assetDetailTemplate = `id:            {{.Id}}
+type:          {{.Type}}
+name:          {{.OriginalFileName}}
+path:          {{.OriginalPath}}
+checksum:      {{.Checksum}}
+device:        {{.DeviceId}} / {{.DeviceAssetId}}
+created:       {{.FileCreatedAt}}
+modified:      {{.FileModifiedAt}}
+local time:    {{.LocalDateTime}}
+updated:       {{.UpdatedAt}}
+duration:      {{.Duration}}
+owner:         {{with .Owner}}{{.FirstName}} {{.LastName}} <{{.Email}}> ({{.Id}}){{else}}{{.OwnerId}}{{end}}
+library:       {{.LibraryId}}
+live photo:    {{value .LivePhotoVideoId}}
+favorite:      {{.IsFavorite}}
+archived:      {{.IsArchived}}
+trashed:       {{.IsTrashed}}
+offline:       {{.IsOffline}}
+read-only:     {{.IsReadOnly}}
+external:      {{.IsExternal}}
+{{- with .ExifInfo}}
+exif:
+  size:        {{with .FileSizeInByte}}{{bytes (value .)}}{{else}}-{{end}}
+  dimensions:  {{value .ExifImageWidth}}x{{value .ExifImageHeight}}
+  camera:      {{value .Make}} {{value .Model}}
+  lens:        {{value .LensModel}}
+  aperture:    {{value .FNumber}}
+  exposure:    {{value .ExposureTime}}
+  iso:         {{value .Iso}}
+  focal:       {{value .FocalLength}}
+  taken:       {{value .DateTimeOriginal}}
+  time zone:   {{value .TimeZone}}
+  modified:    {{value .ModifyDate}}
+  orientation: {{value .Orientation}}
+  projection:  {{value .ProjectionType}}
+  location:    {{value .Latitude}}, {{value .Longitude}}
+  place:       {{value .City}}, {{value .State}}, {{value .Country}}
+  description: {{value .Description}}
+{{- end}}
+{{- with .SmartInfo}}
+smart info:
+  objects:     {{with .Objects}}{{join (value .) ", "}}{{else}}-{{end}}
+  tags:        {{with .Tags}}{{join (value .) ", "}}{{else}}-{{end}}
+{{- end}}
+people:{{with .People}}{{range value .}}
+  - {{if .Name}}{{.Name}}{{else}}(unnamed){{end}} ({{.Id}}){{if .IsHidden}} hidden{{end}}{{end}}{{else}} -{{end}}
+tags:{{with .Tags}}{{range value .}}
+  - {{.Name}} ({{.Type}}, {{.Id}}){{end}}{{else}} -{{end}}
+albums:{{range .Albums}}
+  - {{.AlbumName}} ({{.Id}}){{if .Shared}} shared{{end}}{{else}} -{{end}}
+
+`
+
+// assetAlbum is an album which asset belongs to
+type assetAlbum struct {
+	Id        string `json:"id"`
+	AlbumName string `json:"albumName"`
+	Shared    bool   `json:"shared"`
+}
+
+// assetDetail is an asset with albums it belongs to
+type assetDetail struct {
+	client.AssetResponseDto
+	Albums []assetAlbum `json:"albums"`
+}
+
+type getAssetCmd struct {
+	output outputOptions
+	client client.ClientWithResponsesInterface
+}
+
+func (c *getAssetCmd) getAssetDetail(cmd *cobra.Command, assetId string) (*assetDetail, error) {
+	ids, err := strSliceToIds([]string{assetId})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.GetAssetByIdWithResponse(cmd.Context(), ids[0], &client.GetAssetByIdParams{})
+	if err != nil {
+		log.Errorf("GetAssetById call error: %v", err)
+		return nil, err
+	}
+
+	if resp.JSON200 == nil {
+		return nil, newUnexpectedResponse(resp.StatusCode())
+	}
+
+	albums, err := getAssetAlbums(cmd.Context(), c.client, assetId)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &assetDetail{AssetResponseDto: *resp.JSON200, Albums: []assetAlbum{}}
+	for _, album := range albums {
+		detail.Albums = append(detail.Albums, assetAlbum{Id: album.Id, AlbumName: album.AlbumName, Shared: album.Shared})
+	}
+
+	return detail, nil
+}
+
+func (c *getAssetCmd) run(cmd *cobra.Command, args []string) error {
+	out, err := newPrinter(cmd.OutOrStdout(), c.output, assetColumns)
+	if err != nil {
+		return err
+	}
+
+	c.client = newClient()
+	failed := 0
+	for _, assetId := range args {
+		detail, err := c.getAssetDetail(cmd, assetId)
+		if err != nil {
+			log.Errorf("get asset %s error: %v", assetId, err)
+			failed++
+			continue
+		}
+
+		if err := out.print(*detail); err != nil {
+			return err
+		}
+	}
+
+	if err := out.close(); err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	return newFailureExitError(failed, len(args), "asset(s)")
+}
+
+func GetAssetCmd() *cobra.Command {
+	impl := &getAssetCmd{}
+	cmd := &cobra.Command{
+		Use:  "get_asset <id>...",
+		Args: cobra.MinimumNArgs(1),
+		RunE: impl.run,
+	}
+
+	addOutputFlags(cmd.Flags(), &impl.output, outputTemplate, assetDetailTemplate)
+	return cmd
+}
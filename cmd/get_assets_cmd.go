@@ -1,51 +1,283 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/chain710/immich-cli/client"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 )
 
-func GetAssetsCmd() *cobra.Command {
-	paramsFlagSet := pflag.NewFlagSet("", pflag.ContinueOnError)
-	addFlagSetByFormFields(&client.GetAllAssetsParams{}, paramsFlagSet)
+const defaultAssetTemplate = "id: {{.Id}}, updatedAt: {{.UpdatedAt}}"
 
-	cmd := &cobra.Command{
-		Use: "get_assets",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			cli := newClient()
-			var params client.GetAllAssetsParams
-			if err := setFormFields(&params, paramsFlagSet); err != nil {
-				return err
-			}
+// assetColumn makes an output column of asset, which is printed either as value or pointer
+func assetColumn(name string, value func(asset *client.AssetResponseDto) string) outputColumn {
+	return outputColumn{name: name, value: func(v any) string {
+		switch asset := v.(type) {
+		case client.AssetResponseDto:
+			return value(&asset)
+		case *client.AssetResponseDto:
+			return value(asset)
+		case assetDetail:
+			return value(&asset.AssetResponseDto)
+		default:
+			return fmt.Sprint(v)
+		}
+	}}
+}
+
+var assetColumns = []outputColumn{
+	assetColumn("id", func(a *client.AssetResponseDto) string { return a.Id }),
+	assetColumn("type", func(a *client.AssetResponseDto) string { return string(a.Type) }),
+	assetColumn("name", func(a *client.AssetResponseDto) string { return a.OriginalFileName }),
+	assetColumn("path", func(a *client.AssetResponseDto) string { return a.OriginalPath }),
+	assetColumn("size", func(a *client.AssetResponseDto) string {
+		if a.ExifInfo == nil || a.ExifInfo.FileSizeInByte == nil {
+			return ""
+		}
+		return strconv.FormatInt(*a.ExifInfo.FileSizeInByte, 10)
+	}),
+	assetColumn("createdAt", func(a *client.AssetResponseDto) string { return a.FileCreatedAt.Format(time.RFC3339) }),
+	assetColumn("updatedAt", func(a *client.AssetResponseDto) string { return a.UpdatedAt.Format(time.RFC3339) }),
+	assetColumn("favorite", func(a *client.AssetResponseDto) string { return strconv.FormatBool(a.IsFavorite) }),
+	assetColumn("archived", func(a *client.AssetResponseDto) string { return strconv.FormatBool(a.IsArchived) }),
+	assetColumn("trashed", func(a *client.AssetResponseDto) string { return strconv.FormatBool(a.IsTrashed) }),
+	assetColumn("checksum", func(a *client.AssetResponseDto) string { return a.Checksum }),
+}
+
+// getAssetsCmd prints assets to output. the server returns every asset in one response, so --limit only truncates
+// what's printed, not what's fetched
+type getAssetsCmd struct {
+	limit         int
+	where         string
+	sinceLast     bool
+	statePath     string
+	deletedFile   string
+	output        outputOptions
+	paramsFlagSet *pflag.FlagSet
+}
 
-			resp, err := cli.GetAllAssetsWithResponse(cmd.Context(), &params)
+func (c *getAssetsCmd) run(cmd *cobra.Command, args []string) error {
+	cli := newClient()
+	var params client.GetAllAssetsParams
+	if err := setFormFields(&params, c.paramsFlagSet); err != nil {
+		return err
+	}
+
+	filter, err := parseFilter(c.where)
+	if err != nil {
+		log.Errorf("parse where error: %v", err)
+		return err
+	}
+
+	out, err := newPrinter(cmd.OutOrStdout(), c.output, assetColumns)
+	if err != nil {
+		return err
+	}
 
-			if err != nil {
-				log.Errorf("GetAllAssets call error: %v", err)
+	count := 0
+	handle := func(page []client.AssetResponseDto) error {
+		for _, asset := range page {
+			if c.limit > 0 && count >= c.limit {
+				log.Debugf("reach limit %d", c.limit)
+				return errStopListing
+			}
+			if ok, err := filter.match(asset); err != nil {
 				return err
+			} else if !ok {
+				continue
 			}
-
-			if resp.StatusCode() != http.StatusOK {
-				log.Errorf("Unexpected status code: %v", resp.StatusCode())
-				return newUnexpectedResponse(resp.StatusCode())
+			if err := out.print(asset); err != nil {
+				return err
 			}
+			count++
+		}
+		return nil
+	}
 
-			if resp.JSON200 == nil {
-				log.Warnf("empty data\n")
-				return nil
-			}
+	if c.sinceLast {
+		err = c.listSinceLast(cmd.Context(), cli, params, handle)
+	} else {
+		err = listAssets(cmd.Context(), cli, params, handle)
+	}
+	if err != nil {
+		log.Errorf("list assets error: %v", err)
+		return err
+	}
 
-			assets := *resp.JSON200
-			for _, asset := range assets {
-				cmd.Printf("id: %s, updatedAt: %v\n", asset.Id, asset.UpdatedAt)
-			}
-			return nil
-		},
+	log.Debugf("printed %d assets", count)
+	return out.close()
+}
+
+// clockOverlap is how far client clock goes back when server time is unknown, so skewed clocks don't miss deletions
+const clockOverlap = 5 * time.Minute
+
+// serverTime returns time of response by its Date header, or client clock minus clockOverlap
+func serverTime(resp *http.Response) time.Time {
+	if resp != nil {
+		if t, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			return t
+		}
+	}
+	log.Debugf("no server time in response, use client clock minus %v", clockOverlap)
+	return time.Now().Add(-clockOverlap)
+}
+
+// listSinceLast lists assets updated after last run, and reports assets deleted since then.
+// deletions are reported and state is saved only when listing succeeds, so a failed run is retried next time
+func (c *getAssetsCmd) listSinceLast(ctx context.Context, cli client.ClientWithResponsesInterface,
+	params client.GetAllAssetsParams, handle func(page []client.AssetResponseDto) error) error {
+	if c.statePath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		c.statePath = filepath.Join(home, ".immich_get_assets_state.json")
+	}
+
+	prev, err := loadSyncState(c.statePath)
+	if err != nil {
+		return err
+	}
+
+	// checkedAt is server time before listing, whatever changes during listing is seen again next run
+	next := &syncState{}
+	var deleted []string
+	if prev != nil {
+		if deleted, next.CheckedAt, err = c.getDeleted(ctx, cli, prev.CheckedAt); err != nil {
+			return err
+		}
+		next.UpdatedAfter = prev.UpdatedAfter
+		params.UpdatedAfter = prev.UpdatedAfter
+		if prev.ETag != "" {
+			params.IfNoneMatch = &prev.ETag
+		}
+	}
+
+	// the first page is requested with etag, 304 means nothing changed
+	var skip float32
+	params.Skip = &skip
+	resp, err := cli.GetAllAssetsWithResponse(ctx, &params)
+	if err != nil {
+		return fmt.Errorf("GetAllAssets call error: %w", err)
+	}
+
+	if prev == nil {
+		next.CheckedAt = serverTime(resp.HTTPResponse)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNotModified:
+		log.Infof("nothing new since last run")
+		next.ETag = stringValue(params.IfNoneMatch)
+		return c.finishSinceLast(next, prev, deleted)
+	case http.StatusOK:
+	default:
+		return newUnexpectedResponse(resp.StatusCode())
+	}
+
+	observe := func(page []client.AssetResponseDto) error {
+		for _, asset := range page {
+			next.observe(asset.UpdatedAt)
+		}
+		return handle(page)
+	}
+
+	if resp.JSON200 != nil && len(*resp.JSON200) > 0 {
+		page := *resp.JSON200
+		if err := observe(page); err != nil {
+			return err
+		}
+
+		params.IfNoneMatch = nil
+		skip = float32(len(page))
+		if err := listAssets(ctx, cli, params, observe); err != nil {
+			return err
+		}
+	}
+
+	// etag is of this query, it's only useful if next run sends the same query
+	if sameTime(next.UpdatedAfter, params.UpdatedAfter) {
+		next.ETag = resp.HTTPResponse.Header.Get("ETag")
+	}
+
+	return c.finishSinceLast(next, prev, deleted)
+}
+
+// finishSinceLast reports deletions of a run after last one, then saves state
+func (c *getAssetsCmd) finishSinceLast(next, prev *syncState, deleted []string) error {
+	if prev != nil {
+		if err := c.reportDeleted(deleted); err != nil {
+			return err
+		}
+	}
+
+	return next.save(c.statePath)
+}
+
+// getDeleted returns ids of assets deleted after since, and server time of the response
+func (c *getAssetsCmd) getDeleted(ctx context.Context, cli client.ClientWithResponsesInterface,
+	since time.Time) ([]string, time.Time, error) {
+	resp, err := cli.GetAuditDeletesWithResponse(ctx, &client.GetAuditDeletesParams{
+		EntityType: client.EntityTypeASSET,
+		After:      since,
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("GetAuditDeletes call error: %w", err)
+	}
+	if resp.JSON200 == nil {
+		return nil, time.Time{}, newUnexpectedResponse(resp.StatusCode())
+	}
+
+	if resp.JSON200.NeedsFullSync {
+		log.Warnf("deletions since %v are no longer known, delete %s to list all assets again", since, c.statePath)
+	}
+
+	return resp.JSON200.Ids, serverTime(resp.HTTPResponse), nil
+}
+
+// reportDeleted writes ids of deleted assets to deleted file, or logs them
+func (c *getAssetsCmd) reportDeleted(ids []string) error {
+	if c.deletedFile == "" {
+		for _, id := range ids {
+			log.Infof("asset %s deleted", id)
+		}
+		return nil
+	}
+
+	if ids == nil {
+		ids = []string{}
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.deletedFile, data, 0o644)
+}
+
+func GetAssetsCmd() *cobra.Command {
+	c := getAssetsCmd{paramsFlagSet: pflag.NewFlagSet("", pflag.ContinueOnError)}
+	addFlagSetByFormFields(&client.GetAllAssetsParams{}, c.paramsFlagSet)
+
+	cmd := &cobra.Command{
+		Use:  "get_assets",
+		RunE: c.run,
 	}
 
-	cmd.Flags().AddFlagSet(paramsFlagSet)
+	cmd.Flags().AddFlagSet(c.paramsFlagSet)
+	cmd.Flags().IntVar(&c.limit, "limit", 0, "max num of assets to print, 0 means no limit. all assets are still fetched")
+	cmd.Flags().StringVar(&c.where, "where", "", "client side filter expression, e.g. type == \"VIDEO\" && exif.fileSizeInByte > 50MB")
+	cmd.Flags().BoolVar(&c.sinceLast, "since-last", false, "only list assets updated since last run with --since-last")
+	cmd.Flags().StringVar(&c.statePath, "state", "", "sync state file of --since-last (default is $HOME/.immich_get_assets_state.json)")
+	cmd.Flags().StringVar(&c.deletedFile, "deleted", "", "write ids of assets deleted since last run to this json file, instead of logging them")
+	cmd.MarkFlagsMutuallyExclusive("since-last", "limit")
+	addOutputFlags(cmd.Flags(), &c.output, outputTemplate, defaultAssetTemplate)
 	return cmd
 }
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_GetAssetsSinceLast(t *testing.T) {
+	t1 := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	assets := []client.AssetResponseDto{{Id: "a", UpdatedAt: t2}, {Id: "b", UpdatedAt: t1}}
+	serverNow := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	var auditCalls int
+	var audited []time.Time
+	failListing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Date", serverNow.Format(http.TimeFormat))
+		if r.URL.Path == "/audit/deletes" {
+			auditCalls++
+			after, err := time.Parse(time.RFC3339, r.URL.Query().Get("after"))
+			require.NoError(t, err)
+			audited = append(audited, after)
+			_ = json.NewEncoder(w).Encode(client.AuditDeletesResponseDto{Ids: []string{"c"}})
+			return
+		}
+
+		if failListing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == `"e1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		page := []client.AssetResponseDto{}
+		if r.URL.Query().Get("skip") == "0" && r.URL.Query().Get("updatedAfter") == "" {
+			page = assets
+		}
+		w.Header().Set("ETag", `"e1"`)
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithResponses(server.URL)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	c := getAssetsCmd{statePath: filepath.Join(dir, "state.json"), deletedFile: filepath.Join(dir, "deleted.json")}
+	list := func() []string {
+		var ids []string
+		err := c.listSinceLast(context.Background(), cli, client.GetAllAssetsParams{}, func(page []client.AssetResponseDto) error {
+			for _, asset := range page {
+				ids = append(ids, asset.Id)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	// first run lists everything, etag is of a different query so it's not kept
+	require.Equal(t, []string{"a", "b"}, list())
+	state, err := loadSyncState(c.statePath)
+	require.NoError(t, err)
+	require.True(t, state.UpdatedAfter.Equal(t2))
+	require.Empty(t, state.ETag)
+	require.True(t, state.CheckedAt.Equal(serverNow), "checkpoint is server time")
+	require.Equal(t, 0, auditCalls)
+
+	// listing fails, deletions are not reported and state is kept
+	failListing = true
+	require.Error(t, c.listSinceLast(context.Background(), cli, client.GetAllAssetsParams{},
+		func([]client.AssetResponseDto) error { return nil }))
+	require.NoFileExists(t, c.deletedFile)
+	failed, err := loadSyncState(c.statePath)
+	require.NoError(t, err)
+	require.Equal(t, state, failed)
+	failListing = false
+	serverNow = serverNow.Add(time.Hour)
+
+	// nothing updated, etag is kept
+	require.Empty(t, list())
+	state, err = loadSyncState(c.statePath)
+	require.NoError(t, err)
+	require.Equal(t, `"e1"`, state.ETag)
+	require.Equal(t, 2, auditCalls)
+	require.FileExists(t, c.deletedFile)
+	require.True(t, state.CheckedAt.Equal(serverNow))
+
+	// not modified
+	require.Empty(t, list())
+	state, err = loadSyncState(c.statePath)
+	require.NoError(t, err)
+	require.True(t, state.UpdatedAfter.Equal(t2))
+	require.Equal(t, `"e1"`, state.ETag)
+	require.Equal(t, 3, auditCalls)
+	require.True(t, audited[2].Equal(serverNow), "deletions are audited after last checkpoint")
+}
+
+func Test_ServerTime(t *testing.T) {
+	date := time.Date(2023, 11, 1, 8, 0, 0, 0, time.UTC)
+	resp := &http.Response{Header: http.Header{"Date": []string{date.Format(http.TimeFormat)}}}
+	require.True(t, serverTime(resp).Equal(date))
+
+	// without Date, client clock goes back by overlap
+	before := time.Now()
+	require.WithinDuration(t, before.Add(-clockOverlap), serverTime(&http.Response{}), time.Second)
+	require.WithinDuration(t, before.Add(-clockOverlap), serverTime(nil), time.Second)
+}
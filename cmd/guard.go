@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	guardReasonNotOwned = "not owned"
+	guardReasonShared   = "shared"
+)
+
+// guardEvent is a group whose assets are protected by a safety guard
+type guardEvent struct {
+	Group  []string `json:"group"`
+	Assets []string `json:"assets"`
+	Reason string   `json:"reason"`
+}
+
+// deleteGuard decides which assets of a duplicate group must not be touched
+type deleteGuard struct {
+	ownerId   string          // when not empty, only assets owned by ownerId are considered
+	protected map[string]bool // assets never deleted
+}
+
+func newDeleteGuard(ctx context.Context, cli client.ClientWithResponsesInterface,
+	ownerOnly bool, protectShared bool) (*deleteGuard, error) {
+	guard := &deleteGuard{protected: make(map[string]bool)}
+	if ownerOnly {
+		resp, err := cli.GetMyUserInfoWithResponse(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get my user info error: %w", err)
+		}
+		if resp.JSON200 == nil {
+			return nil, newUnexpectedResponse(resp.StatusCode())
+		}
+		guard.ownerId = resp.JSON200.Id
+		log.Debugf("only assets owned by %s (%s) are deleted", resp.JSON200.Email, guard.ownerId)
+	}
+
+	if protectShared {
+		if err := guard.loadShared(ctx, cli); err != nil {
+			return nil, err
+		}
+		log.Debugf("%d shared assets are protected", len(guard.protected))
+	}
+
+	return guard, nil
+}
+
+// loadShared protects assets in shared albums and shared links
+func (g *deleteGuard) loadShared(ctx context.Context, cli client.ClientWithResponsesInterface) error {
+	shared := true
+	albumsResp, err := cli.GetAllAlbumsWithResponse(ctx, &client.GetAllAlbumsParams{Shared: &shared})
+	if err != nil {
+		return fmt.Errorf("get shared albums error: %w", err)
+	}
+	if albumsResp.JSON200 == nil {
+		return newUnexpectedResponse(albumsResp.StatusCode())
+	}
+
+	albumIds := make(map[string]bool)
+	for _, album := range *albumsResp.JSON200 {
+		albumIds[album.Id] = true
+	}
+
+	linksResp, err := cli.GetAllSharedLinksWithResponse(ctx)
+	if err != nil {
+		return fmt.Errorf("get shared links error: %w", err)
+	}
+	if linksResp.JSON200 == nil {
+		return newUnexpectedResponse(linksResp.StatusCode())
+	}
+
+	for _, link := range *linksResp.JSON200 {
+		for _, asset := range link.Assets {
+			g.protected[asset.Id] = true
+		}
+		if link.Album != nil {
+			albumIds[link.Album.Id] = true
+		}
+	}
+
+	withoutAssets := false
+	for albumId := range albumIds {
+		id, err := uuid.Parse(albumId)
+		if err != nil {
+			return fmt.Errorf("malform uuid: `%s`", albumId)
+		}
+
+		resp, err := cli.GetAlbumInfoWithResponse(ctx, id, &client.GetAlbumInfoParams{WithoutAssets: &withoutAssets})
+		if err != nil {
+			return fmt.Errorf("get album `%s` error: %w", albumId, err)
+		}
+		if resp.JSON200 == nil {
+			return newUnexpectedResponse(resp.StatusCode())
+		}
+
+		for _, asset := range resp.JSON200.Assets {
+			g.protected[asset.Id] = true
+		}
+	}
+
+	return nil
+}
+
+// filterOwned returns assets owned by current user, and ids of others
+func (g *deleteGuard) filterOwned(assets []assetQuality) ([]assetQuality, []string) {
+	if g.ownerId == "" {
+		return assets, nil
+	}
+
+	var owned []assetQuality
+	var others []string
+	for _, aq := range assets {
+		if aq.asset.OwnerId == g.ownerId {
+			owned = append(owned, aq)
+		} else {
+			others = append(others, aq.asset.Id)
+		}
+	}
+
+	return owned, others
+}
+
+func (g *deleteGuard) isProtected(asset *client.AssetResponseDto) bool {
+	return g.protected[asset.Id]
+}
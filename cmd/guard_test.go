@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_FilterOwned(t *testing.T) {
+	assets := func(owners ...string) []assetQuality {
+		var assets []assetQuality
+		for i, owner := range owners {
+			assets = append(assets, assetQuality{asset: &client.AssetResponseDto{Id: string(rune('a' + i)), OwnerId: owner}})
+		}
+		return assets
+	}
+	ids := func(assets []assetQuality) []string {
+		var ids []string
+		for _, aq := range assets {
+			ids = append(ids, aq.asset.Id)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		owner  string
+		assets []assetQuality
+		owned  []string
+		others []string
+	}{
+		{name: "no owner keeps all", owner: "", assets: assets("me", "you"), owned: []string{"a", "b"}},
+		{name: "all owned", owner: "me", assets: assets("me", "me"), owned: []string{"a", "b"}},
+		{name: "mixed", owner: "me", assets: assets("you", "me", "them"), owned: []string{"b"}, others: []string{"a", "c"}},
+		{name: "none owned", owner: "me", assets: assets("you", "them"), others: []string{"a", "b"}},
+		{name: "empty", owner: "me"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := &deleteGuard{ownerId: tt.owner}
+			owned, others := guard.filterOwned(tt.assets)
+			require.Equal(t, tt.owned, ids(owned))
+			require.Equal(t, tt.others, others)
+		})
+	}
+}
+
+func Test_IsProtected(t *testing.T) {
+	tests := []struct {
+		name      string
+		protected map[string]bool
+		id        string
+		expect    bool
+	}{
+		{name: "protected", protected: map[string]bool{"a": true}, id: "a", expect: true},
+		{name: "not protected", protected: map[string]bool{"a": true}, id: "b"},
+		{name: "nothing protected", protected: map[string]bool{}, id: "a"},
+		{name: "nil map", id: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := &deleteGuard{protected: tt.protected}
+			require.Equal(t, tt.expect, guard.isProtected(&client.AssetResponseDto{Id: tt.id}))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"io"
+	"os"
+	"strings"
+)
+
+// readIds reads asset ids from r. input is either one id per line (blank lines and `#` comments are ignored),
+// or a stream of json values: strings, objects with `id`, or arrays of them, e.g. output of `get_assets -o json`
+func readIds(r io.Reader) ([]string, error) {
+	reader := bufio.NewReader(r)
+	first, err := peekNonSpace(reader)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch first {
+	case '[', '{', '"':
+		return readJSONIds(reader)
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, line)
+	}
+
+	return ids, scanner.Err()
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			return b, reader.UnreadByte()
+		}
+	}
+}
+
+func readJSONIds(r io.Reader) ([]string, error) {
+	var ids []string
+	var collect func(v any) error
+	collect = func(v any) error {
+		switch x := v.(type) {
+		case string:
+			ids = append(ids, x)
+		case map[string]any:
+			id, ok := x["id"].(string)
+			if !ok {
+				return fmt.Errorf("json object without id: %v", x)
+			}
+			ids = append(ids, id)
+		case []any:
+			for _, item := range x {
+				if err := collect(item); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected json value: %v", x)
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(r)
+	for {
+		var v any
+		if err := decoder.Decode(&v); errors.Is(err, io.EOF) {
+			return ids, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := collect(v); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// collectIds merges ids in args and ids file, `-` in args or as ids file reads stdin, only once.
+// duplicated ids are removed
+func collectIds(args []string, idsFile string, stdin io.Reader) ([]string, error) {
+	var ids []string
+	stdinRead := false
+	readFrom := func(path string) error {
+		var r io.Reader = stdin
+		if path == "-" {
+			if stdinRead {
+				return errors.New("stdin can only be read once, pass `-` either as arg or as ids file")
+			}
+			stdinRead = true
+		} else {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			r = file
+		}
+
+		more, err := readIds(r)
+		if err != nil {
+			return fmt.Errorf("read ids from `%s` error: %w", path, err)
+		}
+		ids = append(ids, more...)
+		return nil
+	}
+
+	for _, arg := range args {
+		if arg != "-" {
+			ids = append(ids, arg)
+		} else if err := readFrom(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	if idsFile != "" {
+		if err := readFrom(idsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var unique []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique, nil
+}
+
+// chunkIds splits ids into chunks of at most size
+func chunkIds(ids []openapi_types.UUID, size int) [][]openapi_types.UUID {
+	if size <= 0 {
+		size = len(ids)
+	}
+
+	var chunks [][]openapi_types.UUID
+	for len(ids) > 0 {
+		n := minInt(size, len(ids))
+		chunks = append(chunks, ids[:n])
+		ids = ids[n:]
+	}
+	return chunks
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ReadIds(t *testing.T) {
+	tests := []struct {
+		input string
+		ids   []string
+	}{
+		{"", nil},
+		{"a\n\n# comment\n b \nc", []string{"a", "b", "c"}},
+		{`["a", "b"]`, []string{"a", "b"}},
+		{"  [{\"id\": \"a\", \"type\": \"IMAGE\"},\n {\"id\": \"b\"}]", []string{"a", "b"}},
+		{"{\"id\": \"a\"}\n{\"id\": \"b\"}\n", []string{"a", "b"}},
+		{`"a" "b"`, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		ids, err := readIds(strings.NewReader(tt.input))
+		require.NoError(t, err, tt.input)
+		require.Equal(t, tt.ids, ids, tt.input)
+	}
+
+	_, err := readIds(strings.NewReader(`[{"name": "a"}]`))
+	require.Error(t, err)
+	_, err = readIds(strings.NewReader(`[1]`))
+	require.Error(t, err)
+}
+
+func Test_CollectIds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids")
+	require.NoError(t, os.WriteFile(path, []byte("c\na\n"), 0o644))
+
+	ids, err := collectIds([]string{"a", "-", "b"}, path, strings.NewReader(`["b", "d"]`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "d", "c"}, ids)
+
+	_, err = collectIds(nil, filepath.Join(t.TempDir(), "missing"), nil)
+	require.Error(t, err)
+
+	// stdin can't be read twice
+	_, err = collectIds([]string{"-"}, "-", strings.NewReader("a\n"))
+	require.Error(t, err)
+	_, err = collectIds([]string{"-", "-"}, "", strings.NewReader("a\n"))
+	require.Error(t, err)
+}
+
+func Test_ChunkIds(t *testing.T) {
+	var ids []openapi_types.UUID
+	for i := 0; i < 5; i++ {
+		ids = append(ids, uuid.New())
+	}
+
+	chunks := chunkIds(ids, 2)
+	require.Len(t, chunks, 3)
+	require.Len(t, chunks[2], 1)
+	require.Len(t, chunkIds(ids, 0), 1)
+	require.Empty(t, chunkIds(nil, 2))
+}
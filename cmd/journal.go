@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	journalActionTrash     = "trash"
+	journalActionDelete    = "delete" // force delete, can't be undone
+	journalActionArchive   = "archive"
+	journalActionRestore   = "restore"
+	journalActionUnarchive = "unarchive"
+)
+
+// journalEntry is one line of journal file
+type journalEntry struct {
+	Time    time.Time `json:"time"`
+	Run     string    `json:"run"`
+	Command string    `json:"command"`
+	Action  string    `json:"action"`
+	Ids     []string  `json:"ids"`
+	Undoes  string    `json:"undoes,omitempty"` // run id reverted by this entry
+}
+
+// journal appends destructive actions of one run to journal file, safe for concurrent use
+type journal struct {
+	mu      sync.Mutex
+	file    *os.File
+	run     string
+	command string
+	undoes  string
+}
+
+func journalPath() (string, error) {
+	if path := viper.GetString(ViperKey_Journal); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".immich_journal.jsonl"), nil
+}
+
+func openJournal(command string) (*journal, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal `%s` error: %w", path, err)
+	}
+
+	return &journal{file: file, run: uuid.NewString(), command: command}, nil
+}
+
+func (j *journal) record(action string, ids []openapi_types.UUID) error {
+	entry := journalEntry{
+		Time:    time.Now(),
+		Run:     j.run,
+		Command: j.command,
+		Action:  action,
+		Undoes:  j.undoes,
+	}
+	for _, id := range ids {
+		entry.Ids = append(entry.Ids, id.String())
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.file.Write(append(line, '\n'))
+	return err
+}
+
+func (j *journal) Close() error {
+	return j.file.Close()
+}
+
+func readJournal(path string) ([]journalEntry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("malform journal `%s` line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// deleteAction returns journal action of DeleteAssets
+func deleteAction(force bool) string {
+	if force {
+		return journalActionDelete
+	}
+	return journalActionTrash
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Journal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	viper.Set(ViperKey_Journal, path)
+	defer viper.Set(ViperKey_Journal, "")
+
+	id := uuid.New()
+	first, err := openJournal("delete_asset")
+	require.NoError(t, err)
+	require.NoError(t, first.record(journalActionTrash, []openapi_types.UUID{id}))
+	require.NoError(t, first.Close())
+
+	second, err := openJournal("delete_duplicates")
+	require.NoError(t, err)
+	require.NoError(t, second.record(journalActionArchive, []openapi_types.UUID{id}))
+	require.NoError(t, second.Close())
+
+	entries, err := readJournal(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, []string{id.String()}, entries[0].Ids)
+	require.Equal(t, []string{first.run, second.run}, undoableRuns(entries))
+
+	undo, err := openJournal("undo")
+	require.NoError(t, err)
+	undo.undoes = second.run
+	require.NoError(t, undo.record(journalActionUnarchive, []openapi_types.UUID{id}))
+	require.NoError(t, undo.Close())
+
+	entries, err = readJournal(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{first.run}, undoableRuns(entries))
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"github.com/chain710/immich-cli/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// livePhotoIndex links stills and motion videos of live photos across the library
+type livePhotoIndex struct {
+	stills map[string]string // motion video id -> still id
+	videos map[string]string // still id -> motion video id
+}
+
+func newLivePhotoIndex(assets []client.AssetResponseDto) *livePhotoIndex {
+	index := &livePhotoIndex{stills: make(map[string]string), videos: make(map[string]string)}
+	for _, asset := range assets {
+		if asset.LivePhotoVideoId != nil {
+			index.stills[*asset.LivePhotoVideoId] = asset.Id
+			index.videos[asset.Id] = *asset.LivePhotoVideoId
+		}
+	}
+
+	return index
+}
+
+// loadLivePhotoIndex lists the library for live photos, motion videos are not listed but linked by their stills
+func loadLivePhotoIndex(ctx context.Context, cli client.ClientWithResponsesInterface) (*livePhotoIndex, error) {
+	var assets []client.AssetResponseDto
+	err := listAssets(ctx, cli, client.GetAllAssetsParams{}, func(page []client.AssetResponseDto) error {
+		for _, asset := range page {
+			if asset.LivePhotoVideoId != nil {
+				assets = append(assets, asset)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index := newLivePhotoIndex(assets)
+	log.Debugf("%d live photos in library", len(index.videos))
+	return index, nil
+}
+
+// isMotionVideo reports whether id is the motion video of a live photo
+func (i *livePhotoIndex) isMotionVideo(id string) bool {
+	_, ok := i.stills[id]
+	return ok
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// getAssetAlbums returns albums which contain asset
+func getAssetAlbums(ctx context.Context, cli client.ClientWithResponsesInterface,
+	assetId string) ([]client.AlbumResponseDto, error) {
+	id, err := uuid.Parse(assetId)
+	if err != nil {
+		return nil, fmt.Errorf("malform uuid: `%s`", assetId)
+	}
+
+	resp, err := cli.GetAllAlbumsWithResponse(ctx, &client.GetAllAlbumsParams{AssetId: &id})
+	if err != nil {
+		return nil, fmt.Errorf("get albums of `%s` error: %w", assetId, err)
+	}
+
+	if resp.JSON200 == nil {
+		return nil, newUnexpectedResponse(resp.StatusCode())
+	}
+
+	return *resp.JSON200, nil
+}
+
+// mergeMetadata carries albums, tags, favorite, archive state and description of losers over to keeper
+func mergeMetadata(ctx context.Context, cli client.ClientWithResponsesInterface,
+	keeper *client.AssetResponseDto, losers []*client.AssetResponseDto) error {
+	keeperId, err := uuid.Parse(keeper.Id)
+	if err != nil {
+		return fmt.Errorf("malform uuid: `%s`", keeper.Id)
+	}
+
+	return errors.Join(
+		mergeAlbums(ctx, cli, keeperId, losers),
+		mergeTags(ctx, cli, keeper, losers),
+		mergeAssetInfo(ctx, cli, keeper, losers),
+	)
+}
+
+func mergeAlbums(ctx context.Context, cli client.ClientWithResponsesInterface,
+	keeperId uuid.UUID, losers []*client.AssetResponseDto) error {
+	keeperAlbums, err := getAssetAlbums(ctx, cli, keeperId.String())
+	if err != nil {
+		return err
+	}
+
+	joined := make(map[string]bool)
+	for _, album := range keeperAlbums {
+		joined[album.Id] = true
+	}
+
+	var errs []error
+	for _, loser := range losers {
+		albums, err := getAssetAlbums(ctx, cli, loser.Id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, album := range albums {
+			if joined[album.Id] {
+				continue
+			}
+
+			if err := addToAlbum(ctx, cli, album.Id, keeperId); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			log.Debugf("add asset %s to album `%s`", keeperId.String(), album.AlbumName)
+			joined[album.Id] = true
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func addToAlbum(ctx context.Context, cli client.ClientWithResponsesInterface, albumId string, assetId uuid.UUID) error {
+	id, err := uuid.Parse(albumId)
+	if err != nil {
+		return fmt.Errorf("malform uuid: `%s`", albumId)
+	}
+
+	body := client.AddAssetsToAlbumJSONRequestBody{Ids: []uuid.UUID{assetId}}
+	resp, err := cli.AddAssetsToAlbumWithResponse(ctx, id, &client.AddAssetsToAlbumParams{}, body)
+	if err != nil {
+		return fmt.Errorf("add asset to album `%s` error: %w", albumId, err)
+	}
+
+	if resp.JSON200 == nil {
+		return newUnexpectedResponse(resp.StatusCode())
+	}
+
+	for _, result := range *resp.JSON200 {
+		if !result.Success && (result.Error == nil || *result.Error != client.Duplicate) {
+			return fmt.Errorf("add asset %s to album `%s` failed: %v", result.Id, albumId, result.Error)
+		}
+	}
+
+	return nil
+}
+
+func mergeTags(ctx context.Context, cli client.ClientWithResponsesInterface,
+	keeper *client.AssetResponseDto, losers []*client.AssetResponseDto) error {
+	tagged := make(map[string]bool)
+	if keeper.Tags != nil {
+		for _, tag := range *keeper.Tags {
+			tagged[tag.Id] = true
+		}
+	}
+
+	keeperId, err := uuid.Parse(keeper.Id)
+	if err != nil {
+		return fmt.Errorf("malform uuid: `%s`", keeper.Id)
+	}
+
+	var errs []error
+	for _, loser := range losers {
+		if loser.Tags == nil {
+			continue
+		}
+
+		for _, tag := range *loser.Tags {
+			if tagged[tag.Id] {
+				continue
+			}
+
+			if err := tagAsset(ctx, cli, tag.Id, keeperId); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			log.Debugf("tag asset %s with `%s`", keeper.Id, tag.Name)
+			tagged[tag.Id] = true
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func tagAsset(ctx context.Context, cli client.ClientWithResponsesInterface, tagId string, assetId uuid.UUID) error {
+	id, err := uuid.Parse(tagId)
+	if err != nil {
+		return fmt.Errorf("malform uuid: `%s`", tagId)
+	}
+
+	body := client.TagAssetsJSONRequestBody{AssetIds: []uuid.UUID{assetId}}
+	resp, err := cli.TagAssetsWithResponse(ctx, id, body)
+	if err != nil {
+		return fmt.Errorf("tag asset with `%s` error: %w", tagId, err)
+	}
+
+	if resp.JSON200 == nil {
+		return newUnexpectedResponse(resp.StatusCode())
+	}
+
+	for _, result := range *resp.JSON200 {
+		if !result.Success && (result.Error == nil || *result.Error != client.AssetIdsResponseDtoErrorDuplicate) {
+			return fmt.Errorf("tag asset %s with `%s` failed: %v", result.AssetId, tagId, result.Error)
+		}
+	}
+
+	return nil
+}
+
+// mergeAssetInfo ORs favorite, ANDs archive state (keeper stays visible if any copy is), and joins descriptions
+func mergeAssetInfo(ctx context.Context, cli client.ClientWithResponsesInterface,
+	keeper *client.AssetResponseDto, losers []*client.AssetResponseDto) error {
+	favorite, archived := keeper.IsFavorite, keeper.IsArchived
+	var descriptions []string
+	seen := make(map[string]bool)
+	for _, asset := range append([]*client.AssetResponseDto{keeper}, losers...) {
+		favorite = favorite || asset.IsFavorite
+		archived = archived && asset.IsArchived
+		if asset.ExifInfo == nil || asset.ExifInfo.Description == nil {
+			continue
+		}
+
+		description := strings.TrimSpace(*asset.ExifInfo.Description)
+		if description != "" && !seen[description] {
+			seen[description] = true
+			descriptions = append(descriptions, description)
+		}
+	}
+
+	var body client.UpdateAssetJSONRequestBody
+	changed := false
+	if favorite != keeper.IsFavorite {
+		body.IsFavorite, changed = &favorite, true
+	}
+	if archived != keeper.IsArchived {
+		body.IsArchived, changed = &archived, true
+	}
+	if len(descriptions) > 1 {
+		description := strings.Join(descriptions, "\n")
+		body.Description, changed = &description, true
+	} else if len(descriptions) == 1 && (keeper.ExifInfo == nil || keeper.ExifInfo.Description == nil ||
+		strings.TrimSpace(*keeper.ExifInfo.Description) == "") {
+		body.Description, changed = &descriptions[0], true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	keeperId, err := uuid.Parse(keeper.Id)
+	if err != nil {
+		return fmt.Errorf("malform uuid: `%s`", keeper.Id)
+	}
+
+	resp, err := cli.UpdateAssetWithResponse(ctx, keeperId, body)
+	if err != nil {
+		return fmt.Errorf("update asset `%s` error: %w", keeper.Id, err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return newUnexpectedResponse(resp.StatusCode())
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_MergeMetadata(t *testing.T) {
+	description := func(s string) *client.ExifResponseDto {
+		return &client.ExifResponseDto{Description: &s}
+	}
+	tags := func(ids ...string) *[]client.TagResponseDto {
+		var tags []client.TagResponseDto
+		for _, id := range ids {
+			tags = append(tags, client.TagResponseDto{Id: id, Name: id})
+		}
+		return &tags
+	}
+
+	albumA, albumB, tagA, tagB := uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+	keeper := &client.AssetResponseDto{Id: uuid.NewString(), IsArchived: true, Tags: tags(tagA),
+		ExifInfo: description("beach")}
+	loser1 := &client.AssetResponseDto{Id: uuid.NewString(), IsFavorite: true, IsArchived: true, Tags: tags(tagA, tagB),
+		ExifInfo: description(" beach ")}
+	loser2 := &client.AssetResponseDto{Id: uuid.NewString(), Tags: tags(tagB), ExifInfo: description("sunset")}
+
+	cli := newFakeClient()
+	cli.albums[keeper.Id] = []client.AlbumResponseDto{{Id: albumA}}
+	cli.albums[loser1.Id] = []client.AlbumResponseDto{{Id: albumA}, {Id: albumB}}
+	cli.albums[loser2.Id] = []client.AlbumResponseDto{{Id: albumB}}
+
+	require.NoError(t, mergeMetadata(context.Background(), cli, keeper, []*client.AssetResponseDto{loser1, loser2}))
+
+	// keeper joins albums and tags it's missing, once
+	require.Equal(t, map[string][]string{albumB: {keeper.Id}}, cli.added)
+	require.Equal(t, map[string][]string{tagB: {keeper.Id}}, cli.tagged)
+
+	// favorite is ORed, archive is ANDed, distinct descriptions are joined
+	require.Len(t, cli.updates, 1)
+	update := cli.updates[0]
+	require.NotNil(t, update.IsFavorite)
+	require.True(t, *update.IsFavorite)
+	require.NotNil(t, update.IsArchived)
+	require.False(t, *update.IsArchived)
+	require.NotNil(t, update.Description)
+	require.Equal(t, "beach\nsunset", *update.Description)
+}
+
+func Test_MergeAssetInfo(t *testing.T) {
+	description := "kept"
+	tests := []struct {
+		name   string
+		keeper client.AssetResponseDto
+		losers []client.AssetResponseDto
+		want   *client.UpdateAssetJSONRequestBody
+	}{
+		{
+			name:   "nothing to merge",
+			keeper: client.AssetResponseDto{IsFavorite: true},
+			losers: []client.AssetResponseDto{{}},
+		},
+		{
+			name:   "archived keeper stays archived when every copy is",
+			keeper: client.AssetResponseDto{IsArchived: true},
+			losers: []client.AssetResponseDto{{IsArchived: true}},
+		},
+		{
+			name:   "description fills empty keeper",
+			keeper: client.AssetResponseDto{},
+			losers: []client.AssetResponseDto{{ExifInfo: &client.ExifResponseDto{Description: &description}}},
+			want:   &client.UpdateAssetJSONRequestBody{Description: &description},
+		},
+		{
+			name:   "same description is not repeated",
+			keeper: client.AssetResponseDto{ExifInfo: &client.ExifResponseDto{Description: &description}},
+			losers: []client.AssetResponseDto{{ExifInfo: &client.ExifResponseDto{Description: &description}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := newFakeClient()
+			keeper := tt.keeper
+			keeper.Id = uuid.NewString()
+			var losers []*client.AssetResponseDto
+			for i := range tt.losers {
+				losers = append(losers, &tt.losers[i])
+			}
+
+			require.NoError(t, mergeAssetInfo(context.Background(), cli, &keeper, losers))
+			if tt.want == nil {
+				require.Empty(t, cli.updates)
+				return
+			}
+			require.Equal(t, []client.UpdateAssetJSONRequestBody{*tt.want}, cli.updates)
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+const (
+	outputJSON     = "json"
+	outputJSONL    = "jsonl"
+	outputCSV      = "csv"
+	outputTable    = "table"
+	outputYAML     = "yaml"
+	outputTemplate = "template"
+)
+
+// outputOptions are flags of output format shared by commands
+type outputOptions struct {
+	format   string
+	template string
+}
+
+func addOutputFlags(flags *pflag.FlagSet, opts *outputOptions, defaultFormat string, defaultTemplate string) {
+	flags.StringVarP(&opts.format, "output", "o", defaultFormat,
+		"output format: json, jsonl, csv, table, yaml or template")
+	flags.StringVar(&opts.template, "template", defaultTemplate, "go text/template used by template output")
+}
+
+// outputColumn is a column of csv and table output
+type outputColumn struct {
+	name  string
+	value func(v any) string
+}
+
+// printer writes values one by one, so output can be streamed
+type printer interface {
+	print(v any) error
+	// close writes what's left, like the end of json array
+	close() error
+}
+
+var outputFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"bytes": func(n int64) string { return formatBytes(n) },
+	"join":  strings.Join,
+	"value": derefValue,
+}
+
+// derefValue dereferences pointers for templates, nil is `-`
+func derefValue(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "-"
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return "-"
+	}
+	return rv.Interface()
+}
+
+func newPrinter(w io.Writer, opts outputOptions, columns []outputColumn) (printer, error) {
+	switch opts.format {
+	case outputJSON:
+		return &jsonPrinter{w: w}, nil
+	case outputJSONL:
+		return &jsonlPrinter{encoder: json.NewEncoder(w)}, nil
+	case outputCSV:
+		return &columnPrinter{columns: columns, csv: csv.NewWriter(w)}, nil
+	case outputTable:
+		return &columnPrinter{columns: columns, table: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}, nil
+	case outputYAML:
+		return &yamlPrinter{w: w}, nil
+	case outputTemplate:
+		tmpl, err := template.New("output").Funcs(outputFuncs).Parse(opts.template)
+		if err != nil {
+			return nil, fmt.Errorf("parse template error: %w", err)
+		}
+		return &templatePrinter{w: w, tmpl: tmpl, newline: !strings.HasSuffix(opts.template, "\n")}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format `%s`", opts.format)
+	}
+}
+
+// jsonPrinter writes an indented json array
+type jsonPrinter struct {
+	w     io.Writer
+	count int
+}
+
+func (p *jsonPrinter) print(v any) error {
+	data, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if p.count == 0 {
+		sep = "[\n  "
+	}
+	p.count++
+	_, err = fmt.Fprintf(p.w, "%s%s", sep, data)
+	return err
+}
+
+func (p *jsonPrinter) close() error {
+	if p.count == 0 {
+		_, err := io.WriteString(p.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(p.w, "\n]\n")
+	return err
+}
+
+// jsonlPrinter writes one json object per line
+type jsonlPrinter struct {
+	encoder *json.Encoder
+}
+
+func (p *jsonlPrinter) print(v any) error {
+	return p.encoder.Encode(v)
+}
+
+func (p *jsonlPrinter) close() error {
+	return nil
+}
+
+// yamlPrinter writes a yaml sequence. values go through json first, so keys are the same as json output
+type yamlPrinter struct {
+	w     io.Writer
+	count int
+}
+
+func (p *yamlPrinter) print(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+
+	data, err = yaml.Marshal([]any{generic})
+	if err != nil {
+		return err
+	}
+
+	p.count++
+	_, err = p.w.Write(data)
+	return err
+}
+
+func (p *yamlPrinter) close() error {
+	if p.count == 0 {
+		_, err := io.WriteString(p.w, "[]\n")
+		return err
+	}
+	return nil
+}
+
+// columnPrinter writes columns as csv or table, header is written even if there's no value
+type columnPrinter struct {
+	columns []outputColumn
+	csv     *csv.Writer
+	table   *tabwriter.Writer
+	header  bool
+}
+
+func (p *columnPrinter) write(fields []string) error {
+	if p.csv != nil {
+		return p.csv.Write(fields)
+	}
+	_, err := fmt.Fprintln(p.table, strings.Join(fields, "\t"))
+	return err
+}
+
+func (p *columnPrinter) writeHeader() error {
+	if p.header {
+		return nil
+	}
+	p.header = true
+	names := make([]string, len(p.columns))
+	for i, column := range p.columns {
+		names[i] = column.name
+	}
+	return p.write(names)
+}
+
+func (p *columnPrinter) print(v any) error {
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+
+	fields := make([]string, len(p.columns))
+	for i, column := range p.columns {
+		fields[i] = column.value(v)
+	}
+	return p.write(fields)
+}
+
+func (p *columnPrinter) close() error {
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+
+	if p.csv != nil {
+		p.csv.Flush()
+		return p.csv.Error()
+	}
+	return p.table.Flush()
+}
+
+// templatePrinter executes template for every value, appends newline if template doesn't end with one
+type templatePrinter struct {
+	w       io.Writer
+	tmpl    *template.Template
+	newline bool
+}
+
+func (p *templatePrinter) print(v any) error {
+	if err := p.tmpl.Execute(p.w, v); err != nil {
+		return err
+	}
+	if p.newline {
+		_, err := io.WriteString(p.w, "\n")
+		return err
+	}
+	return nil
+}
+
+func (p *templatePrinter) close() error {
+	return nil
+}
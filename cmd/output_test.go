@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func printAll(t *testing.T, opts outputOptions, values ...any) string {
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, opts, assetColumns[:2])
+	require.NoError(t, err)
+	for _, v := range values {
+		require.NoError(t, p.print(v))
+	}
+	require.NoError(t, p.close())
+	return buf.String()
+}
+
+func Test_Printer(t *testing.T) {
+	updatedAt := time.Date(2023, 10, 1, 8, 0, 0, 0, time.UTC)
+	a := client.AssetResponseDto{Id: "a", Type: client.AssetTypeEnumIMAGE, UpdatedAt: updatedAt}
+	b := client.AssetResponseDto{Id: "b", Type: client.AssetTypeEnumVIDEO, UpdatedAt: updatedAt}
+
+	require.Equal(t, "id: a, updatedAt: 2023-10-01 08:00:00 +0000 UTC\n",
+		printAll(t, outputOptions{format: outputTemplate, template: defaultAssetTemplate}, a))
+	require.Equal(t, "a|\nb|\n", printAll(t, outputOptions{format: outputTemplate, template: "{{.Id}}|\n"}, a, &b))
+	require.Equal(t, "id,type\na,IMAGE\nb,VIDEO\n", printAll(t, outputOptions{format: outputCSV}, a, &b))
+	require.Equal(t, "id,type\n", printAll(t, outputOptions{format: outputCSV}))
+	require.Equal(t, "id  type\na   IMAGE\n", printAll(t, outputOptions{format: outputTable}, a))
+	require.Equal(t, "[]\n", printAll(t, outputOptions{format: outputJSON}))
+	require.Equal(t, "[]\n", printAll(t, outputOptions{format: outputYAML}))
+
+	var assets []client.AssetResponseDto
+	require.NoError(t, json.Unmarshal([]byte(printAll(t, outputOptions{format: outputJSON}, a, b)), &assets))
+	require.Equal(t, []string{"a", "b"}, []string{assets[0].Id, assets[1].Id})
+
+	lines := bytes.Split(bytes.TrimSpace([]byte(printAll(t, outputOptions{format: outputJSONL}, a, b))), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	yamlOut := printAll(t, outputOptions{format: outputYAML}, a, b)
+	require.Contains(t, yamlOut, "- checksum: \"\"\n")
+	require.Contains(t, yamlOut, "  id: a\n")
+	require.Contains(t, yamlOut, "  type: VIDEO\n")
+
+	_, err := newPrinter(&bytes.Buffer{}, outputOptions{format: "xml"}, nil)
+	require.Error(t, err)
+}
+
+func Test_AssetDetailTemplate(t *testing.T) {
+	opts := outputOptions{format: outputTemplate, template: assetDetailTemplate}
+	out := printAll(t, opts, assetDetail{AssetResponseDto: client.AssetResponseDto{Id: "a"}})
+	require.Contains(t, out, "id:            a\n")
+	require.Contains(t, out, "live photo:    -\n")
+	require.Contains(t, out, "people: -\n")
+	require.NotContains(t, out, "exif:")
+
+	size, cameraMake, objects := int64(1536), "Apple", []string{"cat", "sofa"}
+	people := []client.PersonResponseDto{{Id: "p", Name: "Alice"}}
+	out = printAll(t, opts, assetDetail{
+		AssetResponseDto: client.AssetResponseDto{
+			Id:        "a",
+			ExifInfo:  &client.ExifResponseDto{FileSizeInByte: &size, Make: &cameraMake},
+			SmartInfo: &client.SmartInfoResponseDto{Objects: &objects},
+			People:    &people,
+		},
+		Albums: []assetAlbum{{Id: "b", AlbumName: "trip", Shared: true}},
+	})
+	require.Contains(t, out, "  size:        1.5 KiB\n")
+	require.Contains(t, out, "  camera:      Apple -\n")
+	require.Contains(t, out, "  objects:     cat, sofa\n")
+	require.Contains(t, out, "people:\n  - Alice (p)\n")
+	require.Contains(t, out, "albums:\n  - trip (b) shared\n")
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// dHash computes 64 bits difference hash of img: shrink to 9x8 grayscale, then compare adjacent pixels
+func dHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	var gray [h][w]float64
+	bounds := img.Bounds()
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/h
+		y1 := maxInt(bounds.Min.Y+(y+1)*bounds.Dy()/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/w
+			x1 := maxInt(bounds.Min.X+(x+1)*bounds.Dx()/w, x0+1)
+			// sample at most 16x16 pixels of each cell
+			stepY, stepX := maxInt((y1-y0)/16, 1), maxInt((x1-x0)/16, 1)
+			var sum float64
+			var n int
+			for py := y0; py < y1; py += stepY {
+				for px := x0; px < x1; px += stepX {
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					n++
+				}
+			}
+			gray[y][x] = sum / float64(n)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// groupByHash groups ids whose hash are within distance of every member of the group, a group starts from the first
+// id not grouped before. it's not transitive, so a chain of slightly different images never collapses into one group.
+// only groups with more than 1 id are returned, ordered by their first ids
+func groupByHash(ids []string, hashes []uint64, distance int) [][]string {
+	grouped := make([]bool, len(ids))
+	duplicates := [][]string{}
+	for i := range ids {
+		if grouped[i] {
+			continue
+		}
+
+		members := []int{i}
+		for j := i + 1; j < len(ids); j++ {
+			if !grouped[j] && withinDistance(hashes, members, j, distance) {
+				grouped[j] = true
+				members = append(members, j)
+			}
+		}
+		if len(members) > 1 {
+			group := make([]string, 0, len(members))
+			for _, m := range members {
+				group = append(group, ids[m])
+			}
+			duplicates = append(duplicates, group)
+		}
+	}
+
+	return duplicates
+}
+
+// withinDistance reports whether hash of j is within distance of every member
+func withinDistance(hashes []uint64, members []int, j int, distance int) bool {
+	for _, m := range members {
+		if hammingDistance(hashes[m], hashes[j]) > distance {
+			return false
+		}
+	}
+	return true
+}
+
+type hashCacheEntry struct {
+	Checksum string `json:"checksum"`
+	Hash     string `json:"hash"`
+}
+
+// hashCache persists perceptual hashes by asset id, an entry is valid until asset's checksum changes
+type hashCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]hashCacheEntry
+}
+
+func loadHashCache(path string) (*hashCache, error) {
+	cache := &hashCache{path: path, entries: make(map[string]hashCacheEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("decode hash cache `%s` error: %w", path, err)
+	}
+
+	return cache, nil
+}
+
+func (c *hashCache) get(asset *client.AssetResponseDto) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[asset.Id]
+	if !ok || entry.Checksum != asset.Checksum {
+		return 0, false
+	}
+
+	hash, err := strconv.ParseUint(entry.Hash, 16, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return hash, true
+}
+
+func (c *hashCache) put(asset *client.AssetResponseDto, hash uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[asset.Id] = hashCacheEntry{Checksum: asset.Checksum, Hash: strconv.FormatUint(hash, 16)}
+}
+
+func (c *hashCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
+
+// getThumbnail downloads thumbnail of asset in format
+func getThumbnail(ctx context.Context, cli client.ClientWithResponsesInterface, assetId string,
+	format client.ThumbnailFormat) ([]byte, error) {
+	id, err := uuid.Parse(assetId)
+	if err != nil {
+		return nil, fmt.Errorf("malform uuid: `%s`", assetId)
+	}
+
+	resp, err := cli.GetAssetThumbnailWithResponse(ctx, id, &client.GetAssetThumbnailParams{Format: &format})
+	if err != nil {
+		return nil, fmt.Errorf("get thumbnail `%s` error: %w", assetId, err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, newUnexpectedResponse(resp.StatusCode())
+	}
+
+	return resp.Body, nil
+}
+
+func perceptualHash(ctx context.Context, cli client.ClientWithResponsesInterface, assetId string) (uint64, error) {
+	thumbnail, err := getThumbnail(ctx, cli, assetId, client.JPEG)
+	if err != nil {
+		return 0, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(thumbnail))
+	if err != nil {
+		return 0, fmt.Errorf("decode thumbnail `%s` error: %w", assetId, err)
+	}
+
+	return dHash(img), nil
+}
+
+// hashAssets computes perceptual hash of every asset with `concurrent` workers, assets failed to hash are skipped
+func hashAssets(ctx context.Context, cli client.ClientWithResponsesInterface, cache *hashCache,
+	assets []client.AssetResponseDto, concurrent int) ([]string, []uint64) {
+	hashes := make([]uint64, len(assets))
+	ok := make([]bool, len(assets))
+	queue := make(chan int, concurrent)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				asset := &assets[index]
+				if hash, hit := cache.get(asset); hit {
+					hashes[index], ok[index] = hash, true
+					continue
+				}
+
+				hash, err := perceptualHash(ctx, cli, asset.Id)
+				if err != nil {
+					log.Warnf("hash asset %s error: %v", asset.Id, err)
+					continue
+				}
+
+				cache.put(asset, hash)
+				hashes[index], ok[index] = hash, true
+			}
+		}()
+	}
+
+	for i := range assets {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	var hashedIds []string
+	var hashed []uint64
+	for i := range assets {
+		if ok[i] {
+			hashedIds = append(hashedIds, assets[i].Id)
+			hashed = append(hashed, hashes[i])
+		}
+	}
+
+	return hashedIds, hashed
+}
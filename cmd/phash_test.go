@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func gradientImage(width, height int, reverse bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8(x * 255 / width)
+			if reverse {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+func Test_DHash(t *testing.T) {
+	small := dHash(gradientImage(90, 80, false))
+	large := dHash(gradientImage(900, 800, false))
+	reversed := dHash(gradientImage(900, 800, true))
+
+	require.Equal(t, uint64(0xffffffffffffffff), small)
+	require.LessOrEqual(t, hammingDistance(small, large), 4)
+	require.Equal(t, 64, hammingDistance(large, reversed))
+}
+
+func Test_GroupByHash(t *testing.T) {
+	ids := []string{"a", "b", "c", "d"}
+	hashes := []uint64{0b0000, 0b1111 << 8, 0b0001, 0b0011}
+	require.Equal(t, [][]string{{"a", "c"}}, groupByHash(ids, hashes, 1))
+	require.Equal(t, [][]string{{"a", "c", "d"}}, groupByHash(ids, hashes, 2))
+	require.Equal(t, [][]string{}, groupByHash(ids, hashes, 0))
+
+	// a chain a-b-c, where a and c are too far apart, isn't one group, whichever comes first
+	chain := []uint64{0b000000, 0b000111, 0b111111}
+	require.Equal(t, [][]string{{"a", "b"}}, groupByHash([]string{"a", "b", "c"}, chain, 3))
+	require.Equal(t, [][]string{{"b", "a"}}, groupByHash([]string{"b", "a", "c"}, []uint64{chain[1], chain[0], chain[2]}, 3))
+}
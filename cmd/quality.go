@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scoreCriterion extracts a comparable value from asset, higher is better
+type scoreCriterion func(asset *client.AssetResponseDto) float64
+
+var rawExtensions = map[string]bool{
+	".dng": true, ".cr2": true, ".cr3": true, ".nef": true, ".arw": true,
+	".raf": true, ".orf": true, ".rw2": true, ".pef": true, ".srw": true,
+}
+
+func boolCriterion(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// oldestHorizon is when `oldest` reaches 0, it goes from 1 at unix epoch to 0 at this time
+var oldestHorizon = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func extension(asset *client.AssetResponseDto) string {
+	return strings.ToLower(filepath.Ext(asset.OriginalPath))
+}
+
+var scoreCriteria = map[string]scoreCriterion{
+	"size": func(asset *client.AssetResponseDto) float64 {
+		if asset.ExifInfo == nil || asset.ExifInfo.FileSizeInByte == nil {
+			return 0
+		}
+		return float64(*asset.ExifInfo.FileSizeInByte)
+	},
+	"resolution": func(asset *client.AssetResponseDto) float64 {
+		if asset.ExifInfo == nil || asset.ExifInfo.ExifImageWidth == nil || asset.ExifInfo.ExifImageHeight == nil {
+			return 0
+		}
+		return float64(*asset.ExifInfo.ExifImageWidth) * float64(*asset.ExifInfo.ExifImageHeight)
+	},
+	"favorite": func(asset *client.AssetResponseDto) float64 {
+		return boolCriterion(asset.IsFavorite)
+	},
+	"raw": func(asset *client.AssetResponseDto) float64 {
+		return boolCriterion(rawExtensions[extension(asset)])
+	},
+	"heic": func(asset *client.AssetResponseDto) float64 {
+		return boolCriterion(extension(asset) == ".heic")
+	},
+	"oldest": func(asset *client.AssetResponseDto) float64 {
+		// scaled to 0..1 like boolean criteria, so it doesn't drown other terms out
+		created := float64(asset.FileCreatedAt.Unix()) / float64(oldestHorizon.Unix())
+		return 1 - math.Max(0, math.Min(1, created))
+	},
+	"gps": func(asset *client.AssetResponseDto) float64 {
+		return boolCriterion(asset.ExifInfo != nil && asset.ExifInfo.Latitude != nil && asset.ExifInfo.Longitude != nil)
+	},
+	"live": func(asset *client.AssetResponseDto) float64 {
+		return boolCriterion(asset.LivePhotoVideoId != nil)
+	},
+}
+
+// scoreTerm is one rule of score chain, spec formats:
+//
+//	name         add criterion value to score
+//	name:weight  add criterion value * weight to score
+//	name*factor  multiply score by factor when criterion value is not zero
+//
+// name is one of scoreCriteria, or `device=<deviceId>`. deviceId may contain `:` or `*`, only a trailing
+// number after them is taken as weight or factor
+type scoreTerm struct {
+	spec     string
+	value    scoreCriterion
+	weight   float64
+	multiply bool
+}
+
+// splitWeight splits `:weight` or `*factor` at i off spec
+func splitWeight(term *scoreTerm, spec string, i int) (string, error) {
+	weight, err := strconv.ParseFloat(spec[i+1:], 64)
+	if err != nil {
+		return "", err
+	}
+	term.weight, term.multiply = weight, spec[i] == '*'
+	return spec[:i], nil
+}
+
+func parseScoreTerm(spec string) (scoreTerm, error) {
+	term := scoreTerm{spec: strings.TrimSpace(spec), weight: 1}
+	if deviceId, ok := strings.CutPrefix(term.spec, "device="); ok {
+		// deviceId may contain `:` or `*`, only a trailing number is taken as weight
+		if i := strings.LastIndexAny(deviceId, ":*"); i >= 0 {
+			if id, err := splitWeight(&term, deviceId, i); err == nil {
+				deviceId = id
+			}
+		}
+		term.value = func(asset *client.AssetResponseDto) float64 {
+			return boolCriterion(asset.DeviceId == deviceId)
+		}
+		return term, nil
+	}
+
+	name := term.spec
+	if i := strings.IndexAny(name, ":*"); i >= 0 {
+		var err error
+		if name, err = splitWeight(&term, name, i); err != nil {
+			return term, fmt.Errorf("malform weight of score rule `%s`: %w", spec, err)
+		}
+	}
+
+	value, ok := scoreCriteria[name]
+	if !ok {
+		return term, fmt.Errorf("unknown score criterion `%s`", name)
+	}
+	term.value = value
+	return term, nil
+}
+
+// assetScorer scores asset by a chain of terms, ties are broken by tieBreakers in order
+type assetScorer struct {
+	terms       []scoreTerm
+	tieBreakers []scoreTerm
+}
+
+func newAssetScorer(scoreBy []string, tieBreakers []string) (*assetScorer, error) {
+	scorer := &assetScorer{}
+	for _, spec := range scoreBy {
+		term, err := parseScoreTerm(spec)
+		if err != nil {
+			return nil, err
+		}
+		scorer.terms = append(scorer.terms, term)
+	}
+
+	for _, spec := range tieBreakers {
+		term, err := parseScoreTerm(spec)
+		if err != nil {
+			return nil, err
+		}
+		scorer.tieBreakers = append(scorer.tieBreakers, term)
+	}
+
+	return scorer, nil
+}
+
+// score returns score of asset, and how each term contributes to it
+func (s *assetScorer) score(asset *client.AssetResponseDto) (float64, []string) {
+	var score float64
+	var explain []string
+	for _, term := range s.terms {
+		if !term.multiply {
+			value := term.value(asset) * term.weight
+			score += value
+			explain = append(explain, fmt.Sprintf("%s=%g", term.spec, value))
+		}
+	}
+
+	for _, term := range s.terms {
+		if term.multiply && term.value(asset) != 0 {
+			score *= term.weight
+			explain = append(explain, term.spec)
+		}
+	}
+
+	for _, term := range s.tieBreakers {
+		explain = append(explain, fmt.Sprintf("tie-breaker %s=%g", term.spec, term.value(asset)*term.weight))
+	}
+
+	return score, explain
+}
+
+// better reports whether a should be kept rather than b
+func (s *assetScorer) better(a, b *assetQuality) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+
+	for _, term := range s.tieBreakers {
+		va, vb := term.value(a.asset)*term.weight, term.value(b.asset)*term.weight
+		if va != vb {
+			return va > vb
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_AssetScorer(t *testing.T) {
+	jpeg := &client.AssetResponseDto{OriginalPath: "/a.jpg", ExifInfo: exifSize(300), DeviceId: "phone",
+		FileCreatedAt: time.Unix(200, 0)}
+	heic := &client.AssetResponseDto{OriginalPath: "/a.HEIC", ExifInfo: exifSize(100), FileCreatedAt: time.Unix(100, 0)}
+
+	// default rules keep the legacy score: file size, x10 for heic
+	scorer, err := newAssetScorer([]string{"size", "heic*10"}, nil)
+	require.NoError(t, err)
+	score, _ := scorer.score(jpeg)
+	require.Equal(t, float64(300), score)
+	score, _ = scorer.score(heic)
+	require.Equal(t, float64(1000), score)
+
+	scorer, err = newAssetScorer([]string{"device=phone:1000", "size:0.5"}, nil)
+	require.NoError(t, err)
+	score, _ = scorer.score(jpeg)
+	require.Equal(t, float64(1150), score)
+
+	scorer, err = newAssetScorer([]string{"favorite"}, []string{"oldest"})
+	require.NoError(t, err)
+	a, b := assetQuality{asset: jpeg}, assetQuality{asset: heic}
+	require.True(t, scorer.better(&b, &a))
+	require.False(t, scorer.better(&a, &b))
+
+	// device id keeps its separators, only a trailing number is weight
+	colon := &client.AssetResponseDto{DeviceId: "aa:bb"}
+	scorer, err = newAssetScorer([]string{"device=aa:bb"}, nil)
+	require.NoError(t, err)
+	score, _ = scorer.score(colon)
+	require.Equal(t, float64(1), score)
+	scorer, err = newAssetScorer([]string{"device=aa:bb:3"}, nil)
+	require.NoError(t, err)
+	score, _ = scorer.score(colon)
+	require.Equal(t, float64(3), score)
+
+	// oldest is within 0..1, so it doesn't outweigh other terms
+	scorer, err = newAssetScorer([]string{"oldest", "heic"}, nil)
+	require.NoError(t, err)
+	score, _ = scorer.score(jpeg)
+	require.Less(t, score, float64(1))
+	require.Greater(t, score, float64(0))
+	heicScore, _ := scorer.score(heic)
+	require.Greater(t, heicScore, float64(1))
+
+	_, err = newAssetScorer([]string{"unknown"}, nil)
+	require.Error(t, err)
+	_, err = newAssetScorer([]string{"size:x"}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	log "github.com/sirupsen/logrus"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reportTemplate renders the page in pieces, so groups are written as soon as they're planned
+const reportTemplate = `{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>immich-cli duplicates report</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+.group { border: 1px solid #ccc; margin-bottom: 1em; padding: 0.5em; }
+.members { display: flex; flex-wrap: wrap; gap: 0.5em; }
+.member { width: 260px; padding: 0.5em; font-size: 12px; word-break: break-all; }
+.member img { max-width: 250px; max-height: 250px; display: block; margin-bottom: 0.3em; }
+.keep { border: 3px solid #2a2; }
+.delete { border: 3px solid #c22; opacity: 0.8; }
+.mark { font-weight: bold; font-size: 14px; }
+.keep .mark { color: #2a2; }
+.delete .mark { color: #c22; }
+.protected { border: 3px dashed #2a2; }
+.protected .mark { color: #2a2; }
+</style>
+</head>
+<body>
+<h1>Duplicates report</h1>
+<p>generated at {{.Time}}, {{.Groups}} group(s) in database, dry-run: {{.DryRun}}</p>
+{{end}}
+{{define "group"}}
+<div class="group" id="group-{{.Index}}">
+<h3>group {{.Index}}</h3>
+<div class="members">
+{{range .Members}}
+<div class="member {{if .Keep}}keep{{else if .Protected}}protected{{else}}delete{{end}}">
+<div class="mark">{{if .Keep}}KEEP{{else if .Protected}}KEEP (protected){{else}}DELETE{{end}}</div>
+{{if .Thumbnail}}<img src="{{.Thumbnail}}" alt="{{.Name}}">{{end}}
+<div><b>{{.Name}}</b></div>
+<div>{{.Id}}</div>
+<div>{{.Path}}</div>
+<div>size: {{.Size}}, dimensions: {{.Dimensions}}</div>
+<div>date: {{.Date}}</div>
+<div>camera: {{.Camera}}</div>
+<div>score: {{.Score}}</div>
+<div>{{.Explain}}</div>
+</div>
+{{end}}
+</div>
+</div>
+{{end}}
+{{define "footer"}}
+<p>{{.Written}} group(s) reported</p>
+</body>
+</html>
+{{end}}`
+
+type reportMember struct {
+	Keep       bool
+	Protected  bool // not the keeper, but guard keeps it
+	Thumbnail  template.URL
+	Id         string
+	Name       string
+	Path       string
+	Size       string
+	Dimensions string
+	Date       string
+	Camera     string
+	Score      float64
+	Explain    string
+}
+
+// duplicatesReport writes every planned group into a self-contained html page as it comes, groups are
+// numbered by their position in database, and written in the order they're planned
+type duplicatesReport struct {
+	client    client.ClientWithResponsesInterface
+	tmpl      *template.Template
+	positions map[string]int
+
+	mu      sync.Mutex // protect file, written, err
+	file    *os.File
+	written int
+	err     error
+}
+
+func newDuplicatesReport(path string, cli client.ClientWithResponsesInterface, dryRun bool,
+	duplicates [][]string) (*duplicatesReport, error) {
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &duplicatesReport{client: cli, tmpl: tmpl, file: file, positions: make(map[string]int)}
+	for i, group := range duplicates {
+		r.positions[groupKey(group)] = i + 1
+	}
+
+	err = tmpl.ExecuteTemplate(file, "header", map[string]any{
+		"Time":   time.Now().Format(time.RFC3339),
+		"DryRun": dryRun,
+		"Groups": len(duplicates),
+	})
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func newReportMember(ctx context.Context, cli client.ClientWithResponsesInterface, aq *assetQuality) reportMember {
+	asset := aq.asset
+	member := reportMember{
+		Id:         asset.Id,
+		Name:       asset.OriginalFileName,
+		Path:       asset.OriginalPath,
+		Size:       "-",
+		Dimensions: "-",
+		Date:       asset.FileCreatedAt.Format(time.DateTime),
+		Camera:     "-",
+		Score:      aq.score,
+		Explain:    strings.Join(aq.explain, ", "),
+	}
+
+	if exif := asset.ExifInfo; exif != nil {
+		if exif.FileSizeInByte != nil {
+			member.Size = formatBytes(*exif.FileSizeInByte)
+		}
+		if exif.ExifImageWidth != nil && exif.ExifImageHeight != nil {
+			member.Dimensions = fmt.Sprintf("%gx%g", *exif.ExifImageWidth, *exif.ExifImageHeight)
+		}
+		if exif.DateTimeOriginal != nil {
+			member.Date = exif.DateTimeOriginal.Format(time.DateTime)
+		}
+		if exif.Make != nil || exif.Model != nil {
+			member.Camera = strings.TrimSpace(stringValue(exif.Make) + " " + stringValue(exif.Model))
+		}
+	}
+
+	thumbnail, err := getThumbnail(ctx, cli, asset.Id, client.WEBP)
+	if err != nil {
+		log.Warnf("get thumbnail of %s error: %v", asset.Id, err)
+	} else {
+		member.Thumbnail = template.URL("data:" + http.DetectContentType(thumbnail) + ";base64," +
+			base64.StdEncoding.EncodeToString(thumbnail))
+	}
+
+	return member
+}
+
+// add fetches thumbnails of plan's members and writes them to report, nothing of group is kept in memory after.
+// members protected by guard are marked as kept, like they are when plan is applied
+func (r *duplicatesReport) add(ctx context.Context, plan *groupPlan, guard *deleteGuard) {
+	var members []reportMember
+	for i := range plan.assets {
+		member := newReportMember(ctx, r.client, &plan.assets[i])
+		member.Keep = i == 0
+		member.Protected = i > 0 && guard.isProtected(plan.assets[i].asset)
+		members = append(members, member)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+
+	r.err = r.tmpl.ExecuteTemplate(r.file, "group", map[string]any{
+		"Index":   r.positions[groupKey(plan.group)],
+		"Members": members,
+	})
+	r.written++
+}
+
+// close finishes the page, returns the first error of writing report
+func (r *duplicatesReport) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.tmpl.ExecuteTemplate(r.file, "footer", map[string]any{"Written": r.written})
+	}
+	if err := r.file.Close(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
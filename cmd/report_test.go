@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DuplicatesReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	plan := func(names ...string) *groupPlan {
+		p := &groupPlan{}
+		for _, name := range names {
+			asset := &client.AssetResponseDto{Id: uuid.NewString(), OriginalFileName: name}
+			p.group = append(p.group, asset.Id)
+			p.assets = append(p.assets, assetQuality{asset: asset})
+		}
+		return p
+	}
+	first, second := plan("a.jpg", "a.heic"), plan("b.jpg", "b.heic", "b.png")
+	guard := &deleteGuard{protected: map[string]bool{second.group[2]: true}}
+
+	report, err := newDuplicatesReport(path, newFakeClient(), true, [][]string{first.group, second.group})
+	require.NoError(t, err)
+
+	// groups are numbered by database position, whatever order they're planned in
+	report.add(context.Background(), second, guard)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "<h3>group 2</h3>")
+	require.Contains(t, string(data), "b.heic")
+	// protected member is kept, like applying plan does
+	require.Equal(t, 1, strings.Count(string(data), "KEEP (protected)"))
+	require.Equal(t, 1, strings.Count(string(data), ">DELETE<"))
+
+	report.add(context.Background(), first, guard)
+	require.NoError(t, report.close())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	page := string(data)
+	require.Less(t, strings.Index(page, "group 2"), strings.Index(page, "group 1"))
+	require.Contains(t, page, "data:image/webp;base64,")
+	require.Contains(t, page, "2 group(s) reported")
+	require.True(t, strings.HasSuffix(strings.TrimSpace(page), "</html>"))
+}
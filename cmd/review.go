@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// reviewDecision is what operator decided for a duplicate group, Delete is members reviewed to be deleted
+type reviewDecision struct {
+	Group  []string `json:"group"`
+	Keeper string   `json:"keeper,omitempty"`
+	Delete []string `json:"delete,omitempty"`
+	Skip   bool     `json:"skip,omitempty"`
+}
+
+// reviewDecisions is a jsonl file of decisions, later decision of the same group wins
+type reviewDecisions struct {
+	path      string
+	mu        sync.Mutex
+	decisions map[string]reviewDecision
+}
+
+func groupKey(group []string) string {
+	ids := append([]string(nil), group...)
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func loadReviewDecisions(path string) (*reviewDecisions, error) {
+	d := &reviewDecisions{path: path, decisions: make(map[string]reviewDecision)}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var decision reviewDecision
+		if err := json.Unmarshal(scanner.Bytes(), &decision); err != nil {
+			return nil, fmt.Errorf("malform decisions `%s` line %d: %w", path, line, err)
+		}
+		d.decisions[groupKey(decision.Group)] = decision
+	}
+
+	return d, scanner.Err()
+}
+
+func (d *reviewDecisions) get(group []string) (reviewDecision, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	decision, ok := d.decisions[groupKey(group)]
+	return decision, ok
+}
+
+// save appends decision to file immediately, so quitting a review keeps what has been reviewed
+func (d *reviewDecisions) save(decision reviewDecision) error {
+	line, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file, err := os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	d.decisions[groupKey(decision.Group)] = decision
+	return nil
+}
+
+var errReviewQuit = errors.New("review quit")
+
+// printPlan prints members of plan as a table, the suggested keeper is marked with `*`
+func printPlan(w io.Writer, plan *groupPlan) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "#\tNAME\tSIZE\tDIMENSIONS\tDATE\tSCORE\tPATH")
+	for i, aq := range plan.assets {
+		asset := aq.asset
+		size, dimensions, date := "-", "-", asset.FileCreatedAt
+		if exif := asset.ExifInfo; exif != nil {
+			if exif.FileSizeInByte != nil {
+				size = formatBytes(*exif.FileSizeInByte)
+			}
+			if exif.ExifImageWidth != nil && exif.ExifImageHeight != nil {
+				dimensions = fmt.Sprintf("%gx%g", *exif.ExifImageWidth, *exif.ExifImageHeight)
+			}
+			if exif.DateTimeOriginal != nil {
+				date = *exif.DateTimeOriginal
+			}
+		}
+
+		mark := " "
+		if i == 0 {
+			mark = "*"
+		}
+		_, _ = fmt.Fprintf(tw, "%s%d\t%s\t%s\t%s\t%s\t%g\t%s\n", mark, i+1, asset.OriginalFileName, size,
+			dimensions, date.Format(time.DateTime), aq.score, asset.OriginalPath)
+	}
+	_ = tw.Flush()
+}
+
+// reviewGroup asks operator what to do with plan, returns errReviewQuit if operator quits
+func reviewGroup(in *bufio.Reader, out io.Writer, plan *groupPlan) (reviewDecision, error) {
+	decision := reviewDecision{Group: plan.group}
+	printPlan(out, plan)
+	for {
+		_, _ = fmt.Fprintf(out, "[enter] keep #1, [1-%d] keep another, [s]kip, [q]uit: ", len(plan.assets))
+		answer, err := in.ReadString('\n')
+		if errors.Is(err, io.EOF) && answer == "" {
+			return decision, errReviewQuit
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return decision, err
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		switch answer {
+		case "":
+			decision.Keeper, decision.Delete = plan.assets[0].asset.Id, plan.loserIds(0)
+			return decision, nil
+		case "s":
+			decision.Skip = true
+			return decision, nil
+		case "q":
+			return decision, errReviewQuit
+		}
+
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(plan.assets) {
+			decision.Keeper, decision.Delete = plan.assets[n-1].asset.Id, plan.loserIds(n-1)
+			return decision, nil
+		}
+		_, _ = fmt.Fprintf(out, "invalid answer `%s`\n", answer)
+	}
+}
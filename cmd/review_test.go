@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ReviewGroup(t *testing.T) {
+	plan := &groupPlan{group: []string{"b", "a"}, assets: []assetQuality{
+		{asset: &client.AssetResponseDto{Id: "a"}, score: 2},
+		{asset: &client.AssetResponseDto{Id: "b"}, score: 1},
+	}}
+
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader("x\n2\n\ns\nq\n"))
+	decision, err := reviewGroup(in, &out, plan)
+	require.NoError(t, err)
+	require.Equal(t, "b", decision.Keeper)
+	require.Equal(t, []string{"a"}, decision.Delete)
+	require.Contains(t, out.String(), "invalid answer `x`")
+	decision, err = reviewGroup(in, &out, plan)
+	require.NoError(t, err)
+	require.Equal(t, "a", decision.Keeper)
+	require.Equal(t, []string{"b"}, decision.Delete)
+	decision, err = reviewGroup(in, &out, plan)
+	require.NoError(t, err)
+	require.True(t, decision.Skip)
+	_, err = reviewGroup(in, &out, plan)
+	require.ErrorIs(t, err, errReviewQuit)
+	_, err = reviewGroup(in, &out, plan)
+	require.ErrorIs(t, err, errReviewQuit)
+
+	require.NoError(t, plan.keep("b"))
+	require.Equal(t, "b", plan.assets[0].asset.Id)
+	require.Error(t, plan.keep("c"))
+}
+
+func Test_ReviewDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	decisions, err := loadReviewDecisions(path)
+	require.NoError(t, err)
+	require.NoError(t, decisions.save(reviewDecision{Group: []string{"a", "b"}, Keeper: "b", Delete: []string{"a"}}))
+
+	decisions, err = loadReviewDecisions(path)
+	require.NoError(t, err)
+	decision, ok := decisions.get([]string{"b", "a"})
+	require.True(t, ok)
+	require.Equal(t, "b", decision.Keeper)
+	require.Equal(t, []string{"a"}, decision.Delete)
+	_, ok = decisions.get([]string{"a"})
+	require.False(t, ok)
+}
+
+func Test_ApplyReviewedDecision(t *testing.T) {
+	keeper := &client.AssetResponseDto{Id: uuid.NewString(), ExifInfo: exifSize(100)}
+	loser := &client.AssetResponseDto{Id: uuid.NewString(), ExifInfo: exifSize(300)}
+	other := &client.AssetResponseDto{Id: uuid.NewString(), ExifInfo: exifSize(200)}
+	group := []string{keeper.Id, loser.Id, other.Id}
+	scorer, err := newAssetScorer([]string{"size"}, nil)
+	require.NoError(t, err)
+	decisions, err := loadReviewDecisions(filepath.Join(t.TempDir(), "decisions.jsonl"))
+	require.NoError(t, err)
+	c := &deleteDuplicatesCmd{client: newFakeClient(keeper, loser, other), scorer: scorer,
+		guard: &deleteGuard{}, dryRun: true, decisions: decisions}
+
+	// reviewed to delete only loser, e.g. other was owned by someone else at review time
+	require.NoError(t, decisions.save(reviewDecision{Group: group, Keeper: keeper.Id, Delete: []string{loser.Id}}))
+	_, err = c.processGroup(context.Background(), group)
+	require.ErrorContains(t, err, "changed since review")
+
+	require.NoError(t, decisions.save(reviewDecision{Group: group, Keeper: keeper.Id,
+		Delete: []string{other.Id, loser.Id}}))
+	item, err := c.processGroup(context.Background(), group)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []openapi_types.UUID{uuid.MustParse(loser.Id), uuid.MustParse(other.Id)}, item.ids)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	groupStatusDone   = "done"
+	groupStatusFailed = "failed"
+)
+
+// groupState is one line of state file, later line of the same group wins
+type groupState struct {
+	Group  string    `json:"group"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// runState persists progress of groups, groups not in state are pending. safe for concurrent use
+type runState struct {
+	mu       sync.Mutex
+	file     *os.File
+	statuses map[string]string
+}
+
+// openRunState loads progress from path if resume, or starts over
+func openRunState(path string, resume bool) (*runState, error) {
+	state := &runState{statuses: make(map[string]string)}
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		if err := state.load(path); err != nil {
+			return nil, err
+		}
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open state `%s` error: %w", path, err)
+	}
+
+	state.file = file
+	return state, nil
+}
+
+func (s *runState) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var state groupState
+		// the last line may be truncated by a crash
+		if err := json.Unmarshal(scanner.Bytes(), &state); err != nil {
+			continue
+		}
+		s.statuses[state.Group] = state.Status
+	}
+
+	return scanner.Err()
+}
+
+func (s *runState) done(group []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statuses[groupKey(group)] == groupStatusDone
+}
+
+func (s *runState) record(group []string, err error) error {
+	state := groupState{Group: groupKey(group), Status: groupStatusDone, Time: time.Now()}
+	if err != nil {
+		state.Status, state.Error = groupStatusFailed, err.Error()
+	}
+
+	line, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[state.Group] = state.Status
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// count returns num of done, failed and pending groups in duplicates
+func (s *runState) count(duplicates [][]string) (done int, failed int, pending int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, group := range duplicates {
+		switch s.statuses[groupKey(group)] {
+		case groupStatusDone:
+			done++
+		case groupStatusFailed:
+			failed++
+		default:
+			pending++
+		}
+	}
+
+	return
+}
+
+func (s *runState) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func Test_RunState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json.state")
+	duplicates := [][]string{{"a", "b"}, {"c", "d"}, {"e", "f"}}
+
+	state, err := openRunState(path, false)
+	require.NoError(t, err)
+	require.NoError(t, state.record(duplicates[0], nil))
+	require.NoError(t, state.record(duplicates[1], errors.New("oops")))
+	require.NoError(t, state.Close())
+
+	state, err = openRunState(path, true)
+	require.NoError(t, err)
+	require.True(t, state.done([]string{"b", "a"}))
+	require.False(t, state.done(duplicates[1]))
+	done, failed, pending := state.count(duplicates)
+	require.Equal(t, []int{1, 1, 1}, []int{done, failed, pending})
+	require.NoError(t, state.record(duplicates[1], nil))
+	require.NoError(t, state.Close())
+
+	state, err = openRunState(path, true)
+	require.NoError(t, err)
+	require.True(t, state.done(duplicates[1]))
+	require.NoError(t, state.Close())
+
+	// start over without resume
+	state, err = openRunState(path, false)
+	require.NoError(t, err)
+	require.False(t, state.done(duplicates[0]))
+	require.NoError(t, state.Close())
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+)
+
+// safetyOptions are flags shared by destructive commands
+type safetyOptions struct {
+	yes   bool
+	iKnow bool
+}
+
+func addSafetyFlags(flags *pflag.FlagSet, opts *safetyOptions) {
+	flags.BoolVar(&opts.yes, "yes", false, "don't ask for confirmation")
+	flags.BoolVar(&opts.iKnow, "i-know-what-im-doing", false, "allow permanent deletion when not running in a terminal")
+}
+
+// isTerminal reports whether in is an interactive terminal
+func isTerminal(in io.Reader) bool {
+	file, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmDelete guards deletion of count assets, in the order of:
+// max-delete threshold, refusing permanent deletion outside a terminal without --i-know-what-im-doing,
+// then --yes or the confirmation prompt. it returns false if user says no
+func (o *safetyOptions) confirmDelete(cmd *cobra.Command, count int, permanent bool, what string) (bool, error) {
+	if limit := viper.GetInt(ViperKey_MaxDelete); limit > 0 && count > limit {
+		return false, fmt.Errorf("%s exceeds max-delete %d, raise it with --%s", what, limit, ViperKey_MaxDelete)
+	}
+
+	interactive := isTerminal(cmd.InOrStdin())
+	if permanent && !interactive && !o.iKnow {
+		return false, errors.New("refuse to permanently delete when not running in a terminal, " +
+			"add --i-know-what-im-doing if you mean it")
+	}
+
+	if o.yes {
+		return true, nil
+	}
+
+	if !interactive {
+		return false, errors.New("not running in a terminal, add --yes to skip confirmation")
+	}
+
+	action := "move to trash"
+	if permanent {
+		action = "PERMANENTLY delete"
+	}
+	return confirm(cmd.InOrStdin(), cmd.OutOrStdout(), fmt.Sprintf("%s %s?", action, what))
+}
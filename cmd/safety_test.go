@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func Test_ConfirmDelete(t *testing.T) {
+	defer viper.Set(ViperKey_MaxDelete, 0)
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&bytes.Buffer{})
+
+	// not a terminal
+	_, err := (&safetyOptions{}).confirmDelete(cmd, 1, false, "1 asset(s)")
+	require.ErrorContains(t, err, "--yes")
+	ok, err := (&safetyOptions{yes: true}).confirmDelete(cmd, 1, false, "1 asset(s)")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = (&safetyOptions{yes: true}).confirmDelete(cmd, 1, true, "1 asset(s)")
+	require.ErrorContains(t, err, "--i-know-what-im-doing")
+	ok, err = (&safetyOptions{yes: true, iKnow: true}).confirmDelete(cmd, 1, true, "1 asset(s)")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	viper.Set(ViperKey_MaxDelete, 2)
+	ok, err = (&safetyOptions{yes: true}).confirmDelete(cmd, 2, false, "2 asset(s)")
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, err = (&safetyOptions{yes: true, iKnow: true}).confirmDelete(cmd, 3, true, "3 asset(s)")
+	require.ErrorContains(t, err, "max-delete 2")
+
+	require.False(t, isTerminal(strings.NewReader("")))
+}
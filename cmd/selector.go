@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"text/tabwriter"
+)
+
+const searchFlagPrefix = "search."
+
+// assetSelector selects assets of a command by ids from args or --ids-file,
+// or by query flags: get_assets flags or search flags, then --where
+type assetSelector struct {
+	idsFile string
+	where   string
+	trashed bool // whether query keeps trashed assets
+
+	paramsFlagSet *pflag.FlagSet
+	searchFlagSet *pflag.FlagSet
+}
+
+func newAssetSelector() *assetSelector {
+	s := &assetSelector{
+		paramsFlagSet: pflag.NewFlagSet("", pflag.ContinueOnError),
+		searchFlagSet: pflag.NewFlagSet("", pflag.ContinueOnError),
+	}
+	addFlagSetByFormFields(&client.GetAllAssetsParams{}, s.paramsFlagSet)
+	addPrefixedFlagSetByFormFields(&client.SearchParams{}, s.searchFlagSet, searchFlagPrefix)
+	return s
+}
+
+// addFlags adds selector flags, action is what command does to selected assets, like `delete`
+func (s *assetSelector) addFlags(flags *pflag.FlagSet, action string) {
+	flags.StringVar(&s.idsFile, "ids-file", "", "read ids from file, one per line or json, - is stdin")
+	flags.StringVar(&s.where, "where", "", action+" assets matching this filter expression")
+	flags.AddFlagSet(s.paramsFlagSet)
+	flags.AddFlagSet(s.searchFlagSet)
+}
+
+// flagSetChanged reports whether any flag of set is changed, set may be parsed as part of command's flags
+func flagSetChanged(set *pflag.FlagSet) bool {
+	changed := false
+	set.VisitAll(func(flag *pflag.Flag) { changed = changed || flag.Changed })
+	return changed
+}
+
+// isQuery reports whether assets are selected by query flags instead of ids
+func (s *assetSelector) isQuery() bool {
+	return s.where != "" || flagSetChanged(s.paramsFlagSet) || flagSetChanged(s.searchFlagSet)
+}
+
+// query resolves assets matching search flags, or get_assets flags, then --where.
+// trashed assets are left out unless trashed is set
+func (s *assetSelector) query(ctx context.Context, cli client.ClientWithResponsesInterface) ([]client.AssetResponseDto, error) {
+	filter, err := parseFilter(s.where)
+	if err != nil {
+		return nil, fmt.Errorf("parse where error: %w", err)
+	}
+
+	var candidates []client.AssetResponseDto
+	if flagSetChanged(s.searchFlagSet) {
+		if flagSetChanged(s.paramsFlagSet) {
+			return nil, errors.New("search flags can't be used with get_assets flags")
+		}
+
+		var params client.SearchParams
+		if err := setPrefixedFormFields(&params, s.searchFlagSet, searchFlagPrefix); err != nil {
+			return nil, err
+		}
+
+		resp, err := cli.SearchWithResponse(ctx, &params)
+		if err != nil {
+			return nil, fmt.Errorf("search call error: %w", err)
+		}
+		if resp.JSON200 == nil {
+			return nil, newUnexpectedResponse(resp.StatusCode())
+		}
+		// search has no paging, a partial result would select assets silently left out
+		result := resp.JSON200.Assets
+		if result.Total > result.Count {
+			return nil, fmt.Errorf("search matches %d assets but returns %d, narrow down the search", result.Total, result.Count)
+		}
+		candidates = result.Items
+	} else {
+		var params client.GetAllAssetsParams
+		if err := setFormFields(&params, s.paramsFlagSet); err != nil {
+			return nil, err
+		}
+
+		err := listAssets(ctx, cli, params, func(page []client.AssetResponseDto) error {
+			candidates = append(candidates, page...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var assets []client.AssetResponseDto
+	for _, asset := range candidates {
+		if asset.IsTrashed && !s.trashed {
+			continue
+		}
+		if ok, err := filter.match(asset); err != nil {
+			return nil, err
+		} else if ok {
+			assets = append(assets, asset)
+		}
+	}
+
+	return assets, nil
+}
+
+// resolve returns ids of args, ids file, or assets matching query. assets are only returned by query
+func (s *assetSelector) resolve(cmd *cobra.Command, cli client.ClientWithResponsesInterface,
+	args []string) ([]openapi_types.UUID, []client.AssetResponseDto, error) {
+	if !s.isQuery() {
+		idArgs, err := collectIds(args, s.idsFile, cmd.InOrStdin())
+		if err != nil {
+			return nil, nil, fmt.Errorf("read ids error: %w", err)
+		}
+
+		if len(idArgs) == 0 {
+			return nil, nil, errors.New("no asset id, pass them as args, `-`, --ids-file or query flags")
+		}
+
+		ids, err := strSliceToIds(idArgs)
+		return ids, nil, err
+	}
+
+	if len(args) > 0 || s.idsFile != "" {
+		return nil, nil, errors.New("ids can't be used with query flags")
+	}
+
+	assets, err := s.query(cmd.Context(), cli)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(assets) == 0 {
+		log.Infof("no asset matches")
+		return nil, nil, nil
+	}
+
+	ids, err := assetIds(assets)
+	return ids, assets, err
+}
+
+// printAssetsPreview prints the first `limit` assets and total size of what action is going to be done to
+func printAssetsPreview(w io.Writer, assets []client.AssetResponseDto, limit int, action string) {
+	var total int64
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, asset := range assets {
+		size := "-"
+		if asset.ExifInfo != nil && asset.ExifInfo.FileSizeInByte != nil {
+			total += *asset.ExifInfo.FileSizeInByte
+			size = formatBytes(*asset.ExifInfo.FileSizeInByte)
+		}
+		if i < limit {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", asset.Id, asset.Type, asset.OriginalFileName, size)
+		}
+	}
+	_ = tw.Flush()
+
+	if len(assets) > limit {
+		fmt.Fprintf(w, "... and %d more\n", len(assets)-limit)
+	}
+
+	fmt.Fprintf(w, "%s %d asset(s), %s in total\n", action, len(assets), formatBytes(total))
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func Test_AssetSelectorResolve(t *testing.T) {
+	video := &client.AssetResponseDto{Id: uuid.NewString(), Type: client.AssetTypeEnumVIDEO}
+	image := &client.AssetResponseDto{Id: uuid.NewString(), Type: client.AssetTypeEnumIMAGE}
+	cli := newFakeClient(video, image)
+
+	resolve := func(stdin string, args ...string) ([]string, int, error) {
+		s := newAssetSelector()
+		cmd := &cobra.Command{}
+		cmd.SetContext(context.Background())
+		cmd.SetIn(strings.NewReader(stdin))
+		s.addFlags(cmd.Flags(), "test")
+		require.NoError(t, cmd.ParseFlags(args))
+		ids, assets, err := s.resolve(cmd, cli, cmd.Flags().Args())
+		var got []string
+		for _, id := range ids {
+			got = append(got, id.String())
+		}
+		return got, len(assets), err
+	}
+
+	ids, assets, err := resolve("", image.Id)
+	require.NoError(t, err)
+	require.Equal(t, []string{image.Id}, ids)
+	require.Zero(t, assets, "ids are not looked up")
+
+	ids, _, err = resolve(video.Id+"\n", "-")
+	require.NoError(t, err)
+	require.Equal(t, []string{video.Id}, ids)
+
+	ids, assets, err = resolve("", `--where=type == "VIDEO"`)
+	require.NoError(t, err)
+	require.Equal(t, []string{video.Id}, ids)
+	require.Equal(t, 1, assets)
+
+	ids, _, err = resolve("", `--where=type == "AUDIO"`)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	_, _, err = resolve("", `--where=type == "VIDEO"`, image.Id)
+	require.Error(t, err)
+
+	_, _, err = resolve("")
+	require.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// runSummary is the machine-readable result of a delete_duplicates run.
+// dry run changes nothing, what it would do is counted in would* fields
+type runSummary struct {
+	DryRun            bool         `json:"dryRun"`
+	Interrupted       bool         `json:"interrupted"`
+	Groups            int          `json:"groups"`
+	Processed         int          `json:"processed"`
+	Pending           int          `json:"pending"`
+	Kept              int          `json:"kept"`
+	Deleted           int          `json:"deleted"`
+	Archived          int          `json:"archived"`
+	WouldDelete       int          `json:"wouldDelete"`
+	WouldArchive      int          `json:"wouldArchive"`
+	Failed            int          `json:"failed"`
+	Skipped           int          `json:"skipped"`
+	BytesReclaimed    int64        `json:"bytesReclaimed"`
+	WouldReclaimBytes int64        `json:"wouldReclaimBytes"`
+	Guarded           []guardEvent `json:"guarded,omitempty"`
+	StartedAt         time.Time    `json:"startedAt"`
+	FinishedAt        time.Time    `json:"finishedAt"`
+}
+
+// write writes summary as json to path, `-` means w
+func (s *runSummary) write(path string, w io.Writer) error {
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// exitError returns error with exit code of interruption if some groups are left pending,
+// otherwise by how many processed groups failed, nil if none failed
+func (s *runSummary) exitError() error {
+	if s.Interrupted {
+		return newExitError(ExitCodeInterrupted, "interrupted with %d group(s) pending", s.Pending)
+	}
+	return newFailureExitError(s.Failed, s.Processed, "processed group(s)")
+}
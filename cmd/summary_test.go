@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_SummaryExitError(t *testing.T) {
+	var exitErr *ExitError
+	require.NoError(t, (&runSummary{Processed: 3}).exitError())
+	require.True(t, errors.As((&runSummary{Processed: 3, Failed: 1}).exitError(), &exitErr))
+	require.Equal(t, ExitCodePartialFailure, exitErr.Code)
+	require.True(t, errors.As((&runSummary{Processed: 3, Failed: 3}).exitError(), &exitErr))
+	require.Equal(t, ExitCodeTotalFailure, exitErr.Code)
+	require.True(t, errors.As((&runSummary{Processed: 3, Pending: 2, Interrupted: true}).exitError(), &exitErr))
+	require.Equal(t, ExitCodeInterrupted, exitErr.Code)
+}
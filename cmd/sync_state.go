@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// syncState is what `get_assets --since-last` saw last time
+type syncState struct {
+	UpdatedAfter *time.Time `json:"updatedAfter,omitempty"` // latest updatedAt of listed assets
+	ETag         string     `json:"etag,omitempty"`         // etag of the first page
+	CheckedAt    time.Time  `json:"checkedAt"`              // server time when last listing started, deletions after it are reported
+}
+
+// loadSyncState returns nil if path does not exist
+func loadSyncState(path string) (*syncState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state syncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode sync state `%s` error: %w", path, err)
+	}
+
+	return &state, nil
+}
+
+func (s *syncState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// observe advances UpdatedAfter to updatedAt if it's later
+func (s *syncState) observe(updatedAt time.Time) {
+	if s.UpdatedAfter == nil || updatedAt.After(*s.UpdatedAfter) {
+		t := updatedAt
+		s.UpdatedAfter = &t
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
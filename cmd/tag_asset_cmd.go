@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type tagAssetCmd struct {
+	tag       string
+	untag     bool
+	chunkSize int
+	preview   int
+	selector  *assetSelector
+
+	client client.ClientWithResponsesInterface
+}
+
+// resolveTag returns id of tag, which is either an id or a tag name
+func (c *tagAssetCmd) resolveTag(ctx context.Context) (openapi_types.UUID, error) {
+	if id, err := uuid.Parse(c.tag); err == nil {
+		return id, nil
+	}
+
+	resp, err := c.client.GetAllTagsWithResponse(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("GetAllTags call error: %w", err)
+	}
+	if resp.JSON200 == nil {
+		return uuid.Nil, newUnexpectedResponse(resp.StatusCode())
+	}
+
+	for _, tag := range *resp.JSON200 {
+		if tag.Name == c.tag {
+			return uuid.Parse(tag.Id)
+		}
+	}
+
+	return uuid.Nil, fmt.Errorf("tag `%s` not found", c.tag)
+}
+
+// tagChunk tags or untags ids, assets already tagged or untagged are not failures
+func (c *tagAssetCmd) tagChunk(ctx context.Context, tagId openapi_types.UUID, ids []openapi_types.UUID) error {
+	body := client.AssetIdsDto{AssetIds: ids}
+	var results *[]client.AssetIdsResponseDto
+	var status int
+	if c.untag {
+		resp, err := c.client.UntagAssetsWithResponse(ctx, tagId, body)
+		if err != nil {
+			return err
+		}
+		results, status = resp.JSON200, resp.StatusCode()
+	} else {
+		resp, err := c.client.TagAssetsWithResponse(ctx, tagId, body)
+		if err != nil {
+			return err
+		}
+		results, status = resp.JSON200, resp.StatusCode()
+	}
+
+	if results == nil {
+		return newUnexpectedResponse(status)
+	}
+
+	// server reports assets already tagged as duplicate, and assets not tagged as not_found when untagging
+	alreadyDone := client.AssetIdsResponseDtoErrorDuplicate
+	if c.untag {
+		alreadyDone = client.AssetIdsResponseDtoErrorNotFound
+	}
+
+	failed := 0
+	for _, result := range *results {
+		if !result.Success && (result.Error == nil || *result.Error != alreadyDone) {
+			log.Warnf("asset %s failed: %v", result.AssetId, stringValue((*string)(result.Error)))
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d asset(s) failed", failed, len(ids))
+	}
+	return nil
+}
+
+func (c *tagAssetCmd) run(cmd *cobra.Command, args []string) error {
+	c.client = newClient()
+	tagId, err := c.resolveTag(cmd.Context())
+	if err != nil {
+		log.Errorf("resolve tag error: %v", err)
+		return err
+	}
+
+	ids, assets, err := c.selector.resolve(cmd, c.client, args)
+	if err != nil {
+		log.Errorf("resolve ids error: %v", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	action := "tag"
+	if c.untag {
+		action = "untag"
+	}
+	if len(assets) > 0 {
+		printAssetsPreview(cmd.OutOrStdout(), assets, c.preview, action)
+	}
+
+	cmd.SilenceUsage = true
+	chunks := chunkIds(ids, c.chunkSize)
+	failed := 0
+	for i, chunk := range chunks {
+		if err := c.tagChunk(cmd.Context(), tagId, chunk); err != nil {
+			log.Errorf("%s chunk %d/%d (%s ... %s) error: %v", action, i+1, len(chunks), chunk[0], chunk[len(chunk)-1], err)
+			failed++
+		}
+	}
+
+	if err := newFailureExitError(failed, len(chunks), "chunk(s)"); err != nil {
+		return err
+	}
+
+	log.Infof("%s %d asset(s) with `%s`", action, len(ids), c.tag)
+	return nil
+}
+
+func TagAssetCmd() *cobra.Command {
+	impl := &tagAssetCmd{selector: newAssetSelector()}
+	cmd := &cobra.Command{
+		Use:   "tag_asset",
+		Short: "tag_asset --tag <tag> [id1] [id2] ... , `-` reads ids from stdin, or query flags select assets",
+		RunE:  impl.run,
+	}
+
+	cmd.Flags().StringVar(&impl.tag, "tag", "", "tag id or name")
+	cobra.CheckErr(cmd.MarkFlagRequired("tag"))
+	cmd.Flags().BoolVar(&impl.untag, "untag", false, "remove tag instead of adding it")
+	cmd.Flags().IntVar(&impl.chunkSize, "chunk-size", 1000, "num of ids per tag request")
+	cmd.Flags().IntVar(&impl.preview, "preview", 10, "num of matching assets to show")
+	impl.selector.addFlags(cmd.Flags(), "tag")
+	return cmd
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_TagAsset(t *testing.T) {
+	duplicate, noPermission := client.AssetIdsResponseDtoErrorDuplicate, client.AssetIdsResponseDtoErrorNoPermission
+	notFound := client.AssetIdsResponseDtoErrorNotFound
+	tagged, denied, missing, fresh := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	cli := newFakeClient()
+	cli.tags = []client.TagResponseDto{{Id: uuid.NewString(), Name: "trip"}}
+	cli.tagErrors = map[openapi_types.UUID]*client.AssetIdsResponseDtoError{
+		tagged: &duplicate, denied: &noPermission, missing: &notFound}
+
+	c := &tagAssetCmd{tag: "trip", client: cli}
+	tagId, err := c.resolveTag(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, cli.tags[0].Id, tagId.String())
+
+	c.tag = "missing"
+	_, err = c.resolveTag(context.Background())
+	require.Error(t, err)
+
+	// already tagged assets are fine
+	require.NoError(t, c.tagChunk(context.Background(), tagId, []openapi_types.UUID{tagged, fresh}))
+	require.Error(t, c.tagChunk(context.Background(), tagId, []openapi_types.UUID{fresh, denied}))
+	// unknown or deleted assets are failures
+	require.Error(t, c.tagChunk(context.Background(), tagId, []openapi_types.UUID{fresh, missing}))
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"github.com/chain710/immich-cli/client"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"io"
+	"net/http"
+	"strings"
+	"testing/iotest"
+)
+
+// exifSize returns exif of file size n
+func exifSize(n int64) *client.ExifResponseDto {
+	return &client.ExifResponseDto{FileSizeInByte: &n}
+}
+
+func okResponse() *http.Response {
+	return &http.Response{StatusCode: http.StatusOK}
+}
+
+// fakeClient fakes immich api by its fields, calls not overridden panic
+type fakeClient struct {
+	client.ClientWithResponsesInterface
+	assets map[string]*client.AssetResponseDto // served by id
+	listed []client.AssetResponseDto           // listed in one page like GetAllAssets, which hides motion videos
+
+	albums    map[string][]client.AlbumResponseDto // albums of asset id
+	added     map[string][]string                  // asset ids added to album id
+	tags      []client.TagResponseDto
+	tagged    map[string][]string                                     // asset ids tagged with tag id
+	tagErrors map[openapi_types.UUID]*client.AssetIdsResponseDtoError // results of tagging asset ids which fail
+	updates   []client.UpdateAssetJSONRequestBody
+
+	missing  map[string]bool // ids failing restore, a whole request fails if any id in it is missing
+	restored []string
+
+	downloads int  // num of DownloadFile calls
+	broken    bool // DownloadFile body breaks halfway
+}
+
+func newFakeClient(assets ...*client.AssetResponseDto) *fakeClient {
+	c := &fakeClient{assets: make(map[string]*client.AssetResponseDto),
+		albums: make(map[string][]client.AlbumResponseDto), added: make(map[string][]string),
+		tagged: make(map[string][]string), tagErrors: make(map[openapi_types.UUID]*client.AssetIdsResponseDtoError),
+		missing: make(map[string]bool)}
+	motions := make(map[string]bool)
+	for _, asset := range assets {
+		c.assets[asset.Id] = asset
+		if asset.LivePhotoVideoId != nil {
+			motions[*asset.LivePhotoVideoId] = true
+		}
+	}
+	for _, asset := range assets {
+		if !motions[asset.Id] {
+			c.listed = append(c.listed, *asset)
+		}
+	}
+	return c
+}
+
+func (c *fakeClient) GetAssetByIdWithResponse(_ context.Context, id openapi_types.UUID,
+	_ *client.GetAssetByIdParams, _ ...client.RequestEditorFn) (*client.GetAssetByIdResponse, error) {
+	asset, ok := c.assets[id.String()]
+	if !ok {
+		return &client.GetAssetByIdResponse{HTTPResponse: &http.Response{StatusCode: http.StatusNotFound}}, nil
+	}
+	return &client.GetAssetByIdResponse{HTTPResponse: okResponse(), JSON200: asset}, nil
+}
+
+func (c *fakeClient) GetAllAssetsWithResponse(_ context.Context, params *client.GetAllAssetsParams,
+	_ ...client.RequestEditorFn) (*client.GetAllAssetsResponse, error) {
+	var page []client.AssetResponseDto
+	if params.Skip == nil || *params.Skip == 0 {
+		page = c.listed
+	}
+	return &client.GetAllAssetsResponse{HTTPResponse: okResponse(), JSON200: &page}, nil
+}
+
+func (c *fakeClient) GetAllAlbumsWithResponse(_ context.Context, params *client.GetAllAlbumsParams,
+	_ ...client.RequestEditorFn) (*client.GetAllAlbumsResponse, error) {
+	albums := c.albums[params.AssetId.String()]
+	return &client.GetAllAlbumsResponse{HTTPResponse: okResponse(), JSON200: &albums}, nil
+}
+
+func (c *fakeClient) AddAssetsToAlbumWithResponse(_ context.Context, id openapi_types.UUID,
+	_ *client.AddAssetsToAlbumParams, body client.AddAssetsToAlbumJSONRequestBody,
+	_ ...client.RequestEditorFn) (*client.AddAssetsToAlbumResponse, error) {
+	var results []client.BulkIdResponseDto
+	for _, assetId := range body.Ids {
+		c.added[id.String()] = append(c.added[id.String()], assetId.String())
+		results = append(results, client.BulkIdResponseDto{Id: assetId.String(), Success: true})
+	}
+	return &client.AddAssetsToAlbumResponse{HTTPResponse: okResponse(), JSON200: &results}, nil
+}
+
+func (c *fakeClient) GetAllTagsWithResponse(_ context.Context,
+	_ ...client.RequestEditorFn) (*client.GetAllTagsResponse, error) {
+	tags := c.tags
+	return &client.GetAllTagsResponse{HTTPResponse: okResponse(), JSON200: &tags}, nil
+}
+
+func (c *fakeClient) TagAssetsWithResponse(_ context.Context, id openapi_types.UUID,
+	body client.TagAssetsJSONRequestBody, _ ...client.RequestEditorFn) (*client.TagAssetsResponse, error) {
+	var results []client.AssetIdsResponseDto
+	for _, assetId := range body.AssetIds {
+		tagErr := c.tagErrors[assetId]
+		if tagErr == nil {
+			c.tagged[id.String()] = append(c.tagged[id.String()], assetId.String())
+		}
+		results = append(results, client.AssetIdsResponseDto{AssetId: assetId.String(), Error: tagErr, Success: tagErr == nil})
+	}
+	return &client.TagAssetsResponse{HTTPResponse: okResponse(), JSON200: &results}, nil
+}
+
+func (c *fakeClient) UpdateAssetWithResponse(_ context.Context, id openapi_types.UUID,
+	body client.UpdateAssetJSONRequestBody, _ ...client.RequestEditorFn) (*client.UpdateAssetResponse, error) {
+	c.updates = append(c.updates, body)
+	return &client.UpdateAssetResponse{HTTPResponse: okResponse(), JSON200: &client.AssetResponseDto{Id: id.String()}}, nil
+}
+
+func (c *fakeClient) RestoreAssetsWithResponse(_ context.Context, body client.RestoreAssetsJSONRequestBody,
+	_ ...client.RequestEditorFn) (*client.RestoreAssetsResponse, error) {
+	for _, id := range body.Ids {
+		if c.missing[id.String()] {
+			return &client.RestoreAssetsResponse{HTTPResponse: &http.Response{StatusCode: http.StatusBadRequest}}, nil
+		}
+	}
+	for _, id := range body.Ids {
+		c.restored = append(c.restored, id.String())
+	}
+	return &client.RestoreAssetsResponse{HTTPResponse: &http.Response{StatusCode: http.StatusNoContent}}, nil
+}
+
+// GetAssetThumbnailWithResponse returns a fake webp thumbnail of any asset
+func (c *fakeClient) GetAssetThumbnailWithResponse(_ context.Context, _ openapi_types.UUID,
+	_ *client.GetAssetThumbnailParams, _ ...client.RequestEditorFn) (*client.GetAssetThumbnailResponse, error) {
+	return &client.GetAssetThumbnailResponse{HTTPResponse: okResponse(), Body: []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")}, nil
+}
+
+// DownloadFile serves id of asset as its original file
+func (c *fakeClient) DownloadFile(_ context.Context, id openapi_types.UUID, _ *client.DownloadFileParams,
+	_ ...client.RequestEditorFn) (*http.Response, error) {
+	c.downloads++
+	var body io.Reader = strings.NewReader(id.String())
+	if c.broken {
+		body = io.MultiReader(body, iotest.ErrReader(errors.New("connection reset")))
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(body)}, nil
+}
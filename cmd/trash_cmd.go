@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net/http"
+	"time"
+)
+
+// trashCmd lists, restores and empties trash
+type trashCmd struct {
+	olderThanDays int
+	all           bool
+	idsFile       string
+	safety        safetyOptions
+	chunkSize     int
+	preview       int
+	output        outputOptions
+
+	client  client.ClientWithResponsesInterface
+	journal *journal
+}
+
+// trashedAsset is the time an asset is trashed, servers newer than the client return it as deletedAt
+type trashedAsset struct {
+	Id        string     `json:"id"`
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+// trashedTimes returns asset id -> deletedAt of time bucket body, assets without deletedAt are left out
+func trashedTimes(body []byte) (map[string]time.Time, error) {
+	var assets []trashedAsset
+	if err := json.Unmarshal(body, &assets); err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]time.Time)
+	for _, asset := range assets {
+		if asset.DeletedAt != nil {
+			times[asset.Id] = *asset.DeletedAt
+		}
+	}
+	return times, nil
+}
+
+// listTrashed returns trashed assets, only those trashed more than --older-than days ago if set
+func (c *trashCmd) listTrashed(ctx context.Context) ([]client.AssetResponseDto, error) {
+	trashed := true
+	bucketsResp, err := c.client.GetTimeBucketsWithResponse(ctx,
+		&client.GetTimeBucketsParams{Size: client.MONTH, IsTrashed: &trashed})
+	if err != nil {
+		return nil, fmt.Errorf("GetTimeBuckets call error: %w", err)
+	}
+	if bucketsResp.JSON200 == nil {
+		return nil, newUnexpectedResponse(bucketsResp.StatusCode())
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -c.olderThanDays)
+	var assets []client.AssetResponseDto
+	for _, bucket := range *bucketsResp.JSON200 {
+		resp, err := c.client.GetByTimeBucketWithResponse(ctx, &client.GetByTimeBucketParams{
+			Size:       client.MONTH,
+			IsTrashed:  &trashed,
+			TimeBucket: bucket.TimeBucket,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("GetByTimeBucket `%s` call error: %w", bucket.TimeBucket, err)
+		}
+		if resp.JSON200 == nil {
+			return nil, newUnexpectedResponse(resp.StatusCode())
+		}
+
+		deletedAt, err := trashedTimes(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("decode time bucket `%s` error: %w", bucket.TimeBucket, err)
+		}
+
+		for _, asset := range *resp.JSON200 {
+			if !asset.IsTrashed {
+				continue
+			}
+			if c.olderThanDays > 0 {
+				trashedAt, ok := deletedAt[asset.Id]
+				if !ok {
+					log.Debugf("asset %s has no deletedAt, use updatedAt as the time it's trashed", asset.Id)
+					trashedAt = asset.UpdatedAt
+				}
+				if !trashedAt.Before(cutoff) {
+					continue
+				}
+			}
+			assets = append(assets, asset)
+		}
+	}
+
+	log.Debugf("%d trashed asset(s) in %d bucket(s)", len(assets), len(*bucketsResp.JSON200))
+	return assets, nil
+}
+
+func (c *trashCmd) openJournal(cmd *cobra.Command) error {
+	var err error
+	c.journal, err = openJournal("trash " + cmd.Name())
+	if err != nil {
+		log.Errorf("open journal error: %v", err)
+	}
+	return err
+}
+
+func assetIds(assets []client.AssetResponseDto) ([]openapi_types.UUID, error) {
+	var ids []string
+	for _, asset := range assets {
+		ids = append(ids, asset.Id)
+	}
+	return strSliceToIds(ids)
+}
+
+func (c *trashCmd) list(cmd *cobra.Command, _ []string) error {
+	out, err := newPrinter(cmd.OutOrStdout(), c.output, assetColumns)
+	if err != nil {
+		return err
+	}
+
+	c.client = newClient()
+	assets, err := c.listTrashed(cmd.Context())
+	if err != nil {
+		log.Errorf("list trash error: %v", err)
+		return err
+	}
+
+	var total int64
+	for _, asset := range assets {
+		if asset.ExifInfo != nil && asset.ExifInfo.FileSizeInByte != nil {
+			total += *asset.ExifInfo.FileSizeInByte
+		}
+		if err := out.print(asset); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("%d trashed asset(s), %s in total", len(assets), formatBytes(total))
+	return out.close()
+}
+
+// restoreChunks restores ids chunk by chunk, a failed chunk is reported and the rest go on
+func (c *trashCmd) restoreChunks(ctx context.Context, ids []openapi_types.UUID) error {
+	chunks := chunkIds(ids, c.chunkSize)
+	failed := 0
+	for i, chunk := range chunks {
+		resp, err := c.client.RestoreAssetsWithResponse(ctx, client.RestoreAssetsJSONRequestBody{Ids: chunk})
+		if err == nil && resp.StatusCode() != http.StatusNoContent {
+			err = newUnexpectedResponse(resp.StatusCode())
+		}
+		if err != nil {
+			log.Errorf("restore chunk %d/%d (%s ... %s) error: %v", i+1, len(chunks), chunk[0], chunk[len(chunk)-1], err)
+			failed++
+		}
+	}
+
+	return newFailureExitError(failed, len(chunks), "chunk(s)")
+}
+
+func (c *trashCmd) restore(cmd *cobra.Command, args []string) error {
+	c.client = newClient()
+	var ids []openapi_types.UUID
+	if c.all {
+		if len(args) > 0 || c.idsFile != "" {
+			return errors.New("ids can't be used with --all")
+		}
+
+		assets, err := c.listTrashed(cmd.Context())
+		if err != nil {
+			log.Errorf("list trash error: %v", err)
+			return err
+		}
+		if ids, err = assetIds(assets); err != nil {
+			return err
+		}
+	} else {
+		if c.olderThanDays > 0 {
+			return errors.New("--older-than only works with --all")
+		}
+
+		idArgs, err := collectIds(args, c.idsFile, cmd.InOrStdin())
+		if err != nil {
+			log.Errorf("read ids error: %v", err)
+			return err
+		}
+		if len(idArgs) == 0 {
+			return errors.New("no asset id, pass them as args, `-`, --ids-file or use --all")
+		}
+		if ids, err = strSliceToIds(idArgs); err != nil {
+			log.Errorf("malformed ids: %v", err)
+			return err
+		}
+	}
+
+	if len(ids) == 0 {
+		log.Infof("nothing to restore")
+		return nil
+	}
+
+	cmd.SilenceUsage = true
+	if c.all && c.olderThanDays <= 0 {
+		resp, err := c.client.RestoreTrashWithResponse(cmd.Context())
+		if err != nil {
+			log.Errorf("restore trash error: %v", err)
+			return err
+		}
+		if resp.StatusCode() != http.StatusNoContent {
+			return newUnexpectedResponse(resp.StatusCode())
+		}
+		log.Infof("restored %d asset(s)", len(ids))
+		return nil
+	}
+
+	if err := c.restoreChunks(cmd.Context(), ids); err != nil {
+		return err
+	}
+
+	log.Infof("restored %d asset(s)", len(ids))
+	return nil
+}
+
+func (c *trashCmd) empty(cmd *cobra.Command, _ []string) error {
+	c.client = newClient()
+	assets, err := c.listTrashed(cmd.Context())
+	if err != nil {
+		log.Errorf("list trash error: %v", err)
+		return err
+	}
+
+	if len(assets) == 0 {
+		log.Infof("nothing to empty")
+		return nil
+	}
+
+	printAssetsPreview(cmd.OutOrStdout(), assets, c.preview, "permanently delete")
+	if ok, err := c.safety.confirmDelete(cmd, len(assets), true, fmt.Sprintf("%d trashed asset(s)", len(assets))); err != nil {
+		return err
+	} else if !ok {
+		log.Infof("aborted")
+		return nil
+	}
+
+	ids, err := assetIds(assets)
+	if err != nil {
+		return err
+	}
+
+	if err := c.openJournal(cmd); err != nil {
+		return err
+	}
+	defer c.journal.Close()
+
+	// only previewed ids are deleted, EmptyTrash would also delete assets trashed after the preview
+	cmd.SilenceUsage = true
+	if err := deleteIdChunks(cmd.Context(), c.client, c.journal, ids, true, c.chunkSize); err != nil {
+		return err
+	}
+
+	log.Infof("permanently deleted %d asset(s)", len(ids))
+	return nil
+}
+
+func TrashCmd() *cobra.Command {
+	impl := &trashCmd{}
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "list, restore or empty trash",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "list trashed assets and their total size",
+		Args:  cobra.NoArgs,
+		RunE:  impl.list,
+	}
+	addOutputFlags(listCmd.Flags(), &impl.output, outputTable, defaultAssetTemplate)
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "restore [id1] [id2] ... , `-` reads ids from stdin, or --all",
+		RunE:  impl.restore,
+	}
+	restoreCmd.Flags().BoolVar(&impl.all, "all", false, "restore all trashed assets")
+	restoreCmd.Flags().StringVar(&impl.idsFile, "ids-file", "", "read ids from file, one per line or json, - is stdin")
+
+	emptyCmd := &cobra.Command{
+		Use:   "empty",
+		Short: "permanently delete trashed assets",
+		Args:  cobra.NoArgs,
+		RunE:  impl.empty,
+	}
+	addSafetyFlags(emptyCmd.Flags(), &impl.safety)
+	emptyCmd.Flags().IntVar(&impl.preview, "preview", 10, "num of trashed assets to show before confirmation")
+
+	for _, sub := range []*cobra.Command{listCmd, restoreCmd, emptyCmd} {
+		sub.Flags().IntVar(&impl.olderThanDays, "older-than", 0, "only assets trashed more than this many days ago")
+		cmd.AddCommand(sub)
+	}
+	restoreCmd.Flags().IntVar(&impl.chunkSize, "chunk-size", 1000, "num of ids per restore request")
+	emptyCmd.Flags().IntVar(&impl.chunkSize, "chunk-size", 1000, "num of ids per delete request")
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/chain710/immich-cli/client"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_ListTrashed(t *testing.T) {
+	// deletedAt is returned by newer servers only
+	type asset struct {
+		client.AssetResponseDto
+		DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	}
+	now := time.Now()
+	daysAgo := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+	buckets := map[string][]asset{
+		"2023-09-01T00:00:00.000Z": {
+			{AssetResponseDto: client.AssetResponseDto{Id: "old", IsTrashed: true, UpdatedAt: *daysAgo(40)}},
+			{AssetResponseDto: client.AssetResponseDto{Id: "live", IsTrashed: false, UpdatedAt: *daysAgo(40)}},
+			// updated after it's trashed
+			{AssetResponseDto: client.AssetResponseDto{Id: "old-updated", IsTrashed: true, UpdatedAt: *daysAgo(1)},
+				DeletedAt: daysAgo(40)},
+		},
+		"2023-10-01T00:00:00.000Z": {
+			{AssetResponseDto: client.AssetResponseDto{Id: "new", IsTrashed: true, UpdatedAt: *daysAgo(1)}},
+			{AssetResponseDto: client.AssetResponseDto{Id: "new-deleted", IsTrashed: true, UpdatedAt: *daysAgo(40)},
+				DeletedAt: daysAgo(1)},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "true", r.URL.Query().Get("isTrashed"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/asset/time-buckets" {
+			var dtos []client.TimeBucketResponseDto
+			for bucket, assets := range buckets {
+				dtos = append(dtos, client.TimeBucketResponseDto{TimeBucket: bucket, Count: len(assets)})
+			}
+			_ = json.NewEncoder(w).Encode(dtos)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(buckets[r.URL.Query().Get("timeBucket")])
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithResponses(server.URL)
+	require.NoError(t, err)
+
+	c := &trashCmd{client: cli}
+	assets, err := c.listTrashed(context.Background())
+	require.NoError(t, err)
+	require.Len(t, assets, 4)
+
+	c.olderThanDays = 30
+	assets, err = c.listTrashed(context.Background())
+	require.NoError(t, err)
+	var ids []string
+	for _, asset := range assets {
+		ids = append(ids, asset.Id)
+	}
+	require.ElementsMatch(t, []string{"old", "old-updated"}, ids)
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chain710/immich-cli/client"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net/http"
+	"time"
+)
+
+type undoCmd struct {
+	run    string
+	list   bool
+	dryRun bool
+
+	client  client.ClientWithResponsesInterface
+	journal *journal
+}
+
+// undoActions maps reversible actions to the action recorded when they're undone
+var undoActions = map[string]string{
+	journalActionTrash:   journalActionRestore,
+	journalActionArchive: journalActionUnarchive,
+}
+
+// revertedIds returns `action:id` already undone, by run
+func revertedIds(entries []journalEntry) map[string]map[string]bool {
+	reverted := make(map[string]map[string]bool)
+	for _, entry := range entries {
+		if entry.Undoes == "" {
+			continue
+		}
+		if reverted[entry.Undoes] == nil {
+			reverted[entry.Undoes] = make(map[string]bool)
+		}
+		for _, id := range entry.Ids {
+			reverted[entry.Undoes][entry.Action+":"+id] = true
+		}
+	}
+	return reverted
+}
+
+// pendingEntries returns reversible entries of run, without ids which are undone already
+func pendingEntries(entries []journalEntry, run string, reverted map[string]map[string]bool) []journalEntry {
+	var pending []journalEntry
+	for _, entry := range entries {
+		undoAction, ok := undoActions[entry.Action]
+		if entry.Run != run || entry.Undoes != "" || !ok {
+			continue
+		}
+
+		var ids []string
+		for _, id := range entry.Ids {
+			if !reverted[run][undoAction+":"+id] {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > 0 {
+			entry.Ids = ids
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// irreversibleEntries returns force delete entries of run, which can't be undone
+func irreversibleEntries(entries []journalEntry, run string) []journalEntry {
+	var irreversible []journalEntry
+	for _, entry := range entries {
+		if entry.Run == run && entry.Undoes == "" && entry.Action == journalActionDelete {
+			irreversible = append(irreversible, entry)
+		}
+	}
+	return irreversible
+}
+
+// journalRuns returns runs in order of their first entry, undo runs are skipped
+func journalRuns(entries []journalEntry) []string {
+	var runs []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !seen[entry.Run] && entry.Undoes == "" {
+			runs = append(runs, entry.Run)
+		}
+		seen[entry.Run] = true
+	}
+	return runs
+}
+
+// undoableRuns returns runs which still have entries to undo, in order of their first entry.
+// undo runs, and runs of only irreversible actions like force delete, are skipped
+func undoableRuns(entries []journalEntry) []string {
+	reverted := revertedIds(entries)
+	var runs []string
+	for _, run := range journalRuns(entries) {
+		if len(pendingEntries(entries, run, reverted)) > 0 {
+			runs = append(runs, run)
+		}
+	}
+
+	return runs
+}
+
+// printIrreversible prints force deleted ids of entries, which are gone for good
+func printIrreversible(cmd *cobra.Command, entries []journalEntry) {
+	for _, entry := range entries {
+		cmd.Printf("cannot be undone (permanently deleted) %d asset(s) at %s, ids: %v\n", len(entry.Ids),
+			entry.Time.Format(time.RFC3339), entry.Ids)
+	}
+}
+
+// listRuns lists runs with entries to undo, and runs which permanently deleted assets with those assets
+func (c *undoCmd) listRuns(cmd *cobra.Command, entries []journalEntry) {
+	reverted := revertedIds(entries)
+	for _, run := range journalRuns(entries) {
+		pending := pendingEntries(entries, run, reverted)
+		irreversible := irreversibleEntries(entries, run)
+		if len(pending) == 0 && len(irreversible) == 0 {
+			continue
+		}
+
+		var first time.Time
+		var command string
+		for _, entry := range entries {
+			if entry.Run == run {
+				first, command = entry.Time, entry.Command
+				break
+			}
+		}
+
+		count := make(map[string]int)
+		for _, entry := range append(pending, irreversible...) {
+			count[entry.Action] += len(entry.Ids)
+		}
+
+		cmd.Printf("run: %s, time: %s, command: %s, trash: %d, archive: %d, delete: %d\n", run,
+			first.Format(time.RFC3339), command,
+			count[journalActionTrash], count[journalActionArchive], count[journalActionDelete])
+		printIrreversible(cmd, irreversible)
+	}
+}
+
+// revert calls api to revert action of ids
+func (c *undoCmd) revert(ctx context.Context, action string, ids []openapi_types.UUID) error {
+	switch action {
+	case journalActionTrash:
+		resp, err := c.client.RestoreAssetsWithResponse(ctx, client.RestoreAssetsJSONRequestBody{Ids: ids})
+		if err != nil {
+			return fmt.Errorf("restore assets error: %w", err)
+		}
+		if resp.StatusCode() != http.StatusNoContent {
+			return newUnexpectedResponse(resp.StatusCode())
+		}
+		return nil
+	case journalActionArchive:
+		archived := false
+		body := client.UpdateAssetsJSONRequestBody{Ids: ids, IsArchived: &archived}
+		resp, err := c.client.UpdateAssetsWithResponse(ctx, body)
+		if err != nil {
+			return fmt.Errorf("unarchive assets error: %w", err)
+		}
+		if resp.StatusCode() != http.StatusNoContent {
+			return newUnexpectedResponse(resp.StatusCode())
+		}
+		return nil
+	default:
+		return fmt.Errorf("can't undo action `%s`", action)
+	}
+}
+
+// undoEntry reverts ids of entry and records those reverted, if the whole entry fails ids are retried one by one,
+// so a partial failure leaves only failed ids to the next undo
+func (c *undoCmd) undoEntry(ctx context.Context, entry journalEntry) error {
+	ids, err := strSliceToIds(entry.Ids)
+	if err != nil {
+		return err
+	}
+
+	if c.dryRun {
+		return nil
+	}
+
+	undoAction := undoActions[entry.Action]
+	err = c.revert(ctx, entry.Action, ids)
+	if err == nil {
+		return c.journal.record(undoAction, ids)
+	}
+	if len(ids) == 1 {
+		return err
+	}
+
+	log.Warnf("undo %s of %d asset(s) error, retry one by one: %v", entry.Action, len(ids), err)
+	var errs []error
+	for _, id := range ids {
+		single := []openapi_types.UUID{id}
+		if err := c.revert(ctx, entry.Action, single); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		if err := c.journal.record(undoAction, single); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *undoCmd) execute(cmd *cobra.Command, _ []string) error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+
+	entries, err := readJournal(path)
+	if err != nil {
+		log.Errorf("read journal error: %v", err)
+		return err
+	}
+
+	if c.list {
+		c.listRuns(cmd, entries)
+		return nil
+	}
+
+	runs := undoableRuns(entries)
+	run := c.run
+	if run == "" {
+		if len(runs) == 0 {
+			cmd.Printf("nothing to undo in %s\n", path)
+			return nil
+		}
+		run = runs[len(runs)-1]
+	}
+
+	// ids undone by a previous, partially failed undo are skipped
+	runEntries := pendingEntries(entries, run, revertedIds(entries))
+	irreversible := irreversibleEntries(entries, run)
+	if len(runEntries) == 0 && len(irreversible) > 0 {
+		printIrreversible(cmd, irreversible)
+		return fmt.Errorf("run `%s` only permanently deleted assets, which cannot be undone", run)
+	}
+	if len(runEntries) == 0 {
+		return fmt.Errorf("run `%s` not found in journal, already undone or has nothing to undo", run)
+	}
+
+	c.client = newClient()
+	if c.journal, err = openJournal("undo"); err != nil {
+		return err
+	}
+	defer c.journal.Close()
+	c.journal.undoes = run
+
+	var errs []error
+	for i := len(runEntries) - 1; i >= 0; i-- {
+		entry := runEntries[i]
+		if err := c.undoEntry(cmd.Context(), entry); err != nil {
+			cmd.Printf("can't undo %s of %d asset(s) at %s: %v, ids: %v\n", entry.Action, len(entry.Ids),
+				entry.Time.Format(time.RFC3339), err, entry.Ids)
+			errs = append(errs, err)
+			continue
+		}
+
+		cmd.Printf("undo %s of %d asset(s) at %s, dryRun: %v\n", entry.Action, len(entry.Ids),
+			entry.Time.Format(time.RFC3339), c.dryRun)
+	}
+
+	printIrreversible(cmd, irreversible)
+	return errors.Join(errs...)
+}
+
+func UndoCmd() *cobra.Command {
+	impl := &undoCmd{}
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "undo a run of destructive commands recorded in journal, latest run by default",
+		RunE:  impl.execute,
+	}
+
+	cmd.Flags().StringVar(&impl.run, "run", "", "run id to undo, see --list")
+	cmd.Flags().BoolVar(&impl.list, "list", false, "list runs which can be undone, and assets they permanently deleted")
+	cmd.Flags().BoolVar(&impl.dryRun, "dry-run", false, "don't actually undo")
+	return cmd
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_UndoableRuns(t *testing.T) {
+	a, b := uuid.NewString(), uuid.NewString()
+	entries := []journalEntry{
+		{Run: "trash", Action: journalActionTrash, Ids: []string{a, b}},
+		{Run: "archive", Action: journalActionArchive, Ids: []string{a}},
+		{Run: "force", Action: journalActionDelete, Ids: []string{b}},
+	}
+	require.Equal(t, []string{"trash", "archive"}, undoableRuns(entries))
+
+	// partially undone run keeps what's left
+	entries = append(entries, journalEntry{Run: "undo", Action: journalActionRestore, Ids: []string{a}, Undoes: "trash"})
+	require.Equal(t, []string{"trash", "archive"}, undoableRuns(entries))
+	pending := pendingEntries(entries, "trash", revertedIds(entries))
+	require.Len(t, pending, 1)
+	require.Equal(t, []string{b}, pending[0].Ids)
+
+	entries = append(entries, journalEntry{Run: "undo2", Action: journalActionRestore, Ids: []string{b}, Undoes: "trash"})
+	require.Equal(t, []string{"archive"}, undoableRuns(entries))
+}
+
+func Test_UndoEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	viper.Set(ViperKey_Journal, path)
+	defer viper.Set(ViperKey_Journal, "")
+
+	ok1, missing, ok2 := uuid.New(), uuid.New(), uuid.New()
+	trash, err := openJournal("delete_asset")
+	require.NoError(t, err)
+	require.NoError(t, trash.record(journalActionTrash, []openapi_types.UUID{ok1, missing, ok2}))
+	require.NoError(t, trash.Close())
+
+	cli := newFakeClient()
+	cli.missing[missing.String()] = true
+	undo := &undoCmd{client: cli}
+	undo.journal, err = openJournal("undo")
+	require.NoError(t, err)
+	undo.journal.undoes = trash.run
+
+	entries, err := readJournal(path)
+	require.NoError(t, err)
+	require.Error(t, undo.undoEntry(context.Background(), entries[0]))
+	require.NoError(t, undo.journal.Close())
+	require.Equal(t, []string{ok1.String(), ok2.String()}, cli.restored)
+
+	// only the failed id is left to undo
+	entries, err = readJournal(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{trash.run}, undoableRuns(entries))
+	pending := pendingEntries(entries, trash.run, revertedIds(entries))
+	require.Len(t, pending, 1)
+	require.Equal(t, []string{missing.String()}, pending[0].Ids)
+}
+
+func Test_UndoIrreversible(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	viper.Set(ViperKey_Journal, path)
+	defer viper.Set(ViperKey_Journal, "")
+
+	trashed, deleted := uuid.New(), uuid.New()
+	force, err := openJournal("delete_asset")
+	require.NoError(t, err)
+	require.NoError(t, force.record(journalActionDelete, []openapi_types.UUID{deleted}))
+	require.NoError(t, force.Close())
+	mixed, err := openJournal("delete_duplicates")
+	require.NoError(t, err)
+	require.NoError(t, mixed.record(journalActionTrash, []openapi_types.UUID{trashed}))
+	require.NoError(t, mixed.record(journalActionDelete, []openapi_types.UUID{deleted}))
+	require.NoError(t, mixed.Close())
+
+	execute := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := UndoCmd()
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		cmd.SilenceErrors, cmd.SilenceUsage = true, true
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// force only runs are listed with what's gone
+	out, err := execute("--list")
+	require.NoError(t, err)
+	require.Contains(t, out, "run: "+force.run)
+	require.Contains(t, out, "run: "+mixed.run)
+	require.Equal(t, 2, strings.Count(out, "cannot be undone (permanently deleted) 1 asset(s)"))
+	require.Contains(t, out, deleted.String())
+
+	out, err = execute("--run", force.run)
+	require.ErrorContains(t, err, "cannot be undone")
+	require.Contains(t, out, deleted.String())
+
+	out, err = execute("--run", mixed.run, "--dry-run")
+	require.NoError(t, err)
+	require.Contains(t, out, "undo trash of 1 asset(s)")
+	require.Contains(t, out, "cannot be undone (permanently deleted) 1 asset(s)")
+}
@@ -6,16 +6,24 @@ import (
 	"fmt"
 	"github.com/chain710/immich-cli/client"
 	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func newClient() client.ClientWithResponsesInterface {
+	return newAPIClient()
+}
+
+// newAPIClient also exposes raw responses, like streamed downloads which *WithResponse methods buffer in memory
+func newAPIClient() *client.ClientWithResponses {
 	api := viper.GetString(ViperKey_API)
 	key := viper.GetString(ViperKey_APIKey)
 
@@ -43,6 +51,82 @@ func newClient() client.ClientWithResponsesInterface {
 	return cli
 }
 
+// errStopListing is returned by fn of listAssets to stop listing without error
+var errStopListing = errors.New("stop listing")
+
+// listAssets calls GetAllAssets by advancing `skip` until a response is empty, fn is called with every non-empty one.
+// the server has no page size, it returns every asset after skip, so it's one full response and an empty one
+func listAssets(ctx context.Context, cli client.ClientWithResponsesInterface,
+	params client.GetAllAssetsParams, fn func(page []client.AssetResponseDto) error) error {
+	var skip float32
+	if params.Skip != nil {
+		skip = *params.Skip
+	}
+
+	for {
+		pageSkip := skip
+		params.Skip = &pageSkip
+		resp, err := cli.GetAllAssetsWithResponse(ctx, &params)
+		if err != nil {
+			return fmt.Errorf("GetAllAssets call error: %w", err)
+		}
+
+		if resp.StatusCode() != http.StatusOK {
+			return newUnexpectedResponse(resp.StatusCode())
+		}
+
+		if resp.JSON200 == nil || len(*resp.JSON200) == 0 {
+			return nil
+		}
+
+		page := *resp.JSON200
+		log.Debugf("got %d assets, skip: %v", len(page), skip)
+		if err := fn(page); errors.Is(err, errStopListing) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		skip += float32(len(page))
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// formatBytes formats n in human-readable binary units, like 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func parseOptions(s string) []string {
 	var ss []string
 	segments := strings.Split(s, ",")
@@ -53,6 +137,12 @@ func parseOptions(s string) []string {
 }
 
 func addFlagSetByFormFields(s any, set *pflag.FlagSet) {
+	addPrefixedFlagSetByFormFields(s, set, "")
+}
+
+// addPrefixedFlagSetByFormFields is addFlagSetByFormFields with prefix before every flag name,
+// so params sharing form names can be used by the same command
+func addPrefixedFlagSetByFormFields(s any, set *pflag.FlagSet, prefix string) {
 	typeInfo := resolveElem(reflect.ValueOf(s)).Type()
 	for i := 0; i < typeInfo.NumField(); i++ {
 		fieldType := typeInfo.Field(i)
@@ -65,37 +155,60 @@ func addFlagSetByFormFields(s any, set *pflag.FlagSet) {
 			continue
 		}
 
-		name := options[0]
-		set.Var(&genericVar{t: fieldType.Type.String()}, name, "")
+		name := prefix + options[0]
+		isSlice := resolveType(fieldType.Type).Kind() == reflect.Slice
+		set.Var(&genericVar{t: fieldType.Type.String(), slice: isSlice}, name, formFieldUsage(fieldType.Type))
+		if isRequiredFormField(fieldType.Type, options) {
+			_ = cobra.MarkFlagRequired(set, name)
+		}
 	}
 }
 
 func setFormFields(s any, set *pflag.FlagSet) error {
-	tagFields, err := validateAndGetFieldMap(s)
+	return setPrefixedFormFields(s, set, "")
+}
+
+// setPrefixedFormFields sets fields of s by flags added by addPrefixedFlagSetByFormFields with the same prefix
+func setPrefixedFormFields(s any, set *pflag.FlagSet, prefix string) error {
+	tagFields, required, err := validateAndGetFieldMap(s)
 	if err != nil {
 		return err
 	}
 
+	// required fields are checked here as well, cobra only checks flags of the command being executed
 	var errs []error
+	for _, name := range required {
+		if flag := set.Lookup(prefix + name); flag != nil && !flag.Changed {
+			errs = append(errs, fmt.Errorf("required flag `%s` not set", prefix+name))
+		}
+	}
+
 	set.VisitAll(func(flag *pflag.Flag) {
-		if !flag.Changed {
+		if !flag.Changed || !strings.HasPrefix(flag.Name, prefix) {
 			return
 		}
 
-		fieldValue, ok := tagFields[flag.Name]
+		fieldValue, ok := tagFields[strings.TrimPrefix(flag.Name, prefix)]
 		if !ok {
 			log.Debugf("no flag `%s` in params field", flag.Name)
 			return
 		}
 
+		values := []string{flag.Value.String()}
+		if v, ok := flag.Value.(*genericVar); ok && v.slice {
+			values = v.values
+		}
+
 		log.Debugf("ready to set value by flag: %s, value: %s", flag.Name, flag.Value.String())
 		switch fieldValue.Kind() {
 		case reflect.Pointer:
-			if err := setPointerField(fieldValue, flag.Value.String()); err != nil {
-				errs = append(errs, err)
+			if err := setPointerField(fieldValue, values); err != nil {
+				errs = append(errs, fmt.Errorf("flag `%s`: %w", flag.Name, err))
 			}
 		default:
-			errs = append(errs, fmt.Errorf("unsupport field type %s", fieldValue.Type().String()))
+			if err := setValue(fieldValue, values); err != nil {
+				errs = append(errs, fmt.Errorf("flag `%s`: %w", flag.Name, err))
+			}
 		}
 	})
 
@@ -110,53 +223,214 @@ func resolveElem(value reflect.Value) reflect.Value {
 	return value
 }
 
-func setPointerField(ptrField reflect.Value, value string) error {
-	elemType := ptrField.Type().Elem()
-	newValue := reflect.New(elemType) // NOTE: newValue is a pointer type
+// resolveType returns type pointed by t
+func resolveType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// enumValues are known values of named string types in params, flags of them are validated
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(client.EntityType("")):                 {string(client.EntityTypeALBUM), string(client.EntityTypeASSET)},
+	reflect.TypeOf(client.GetPartnersParamsDirection("")): {string(client.SharedBy), string(client.SharedWith)},
+	reflect.TypeOf(client.SearchParamsType("")): {
+		string(client.SearchParamsTypeIMAGE),
+		string(client.SearchParamsTypeVIDEO),
+		string(client.SearchParamsTypeAUDIO),
+		string(client.SearchParamsTypeOTHER),
+	},
+	reflect.TypeOf(client.ThumbnailFormat("")): {string(client.JPEG), string(client.WEBP)},
+	reflect.TypeOf(client.TimeBucketSize("")):  {string(client.DAY), string(client.MONTH)},
+}
+
+// formFieldUsage describes what value flag of type t takes
+func formFieldUsage(t reflect.Type) string {
+	elemType := resolveType(t)
+	var usage string
+	if elemType.Kind() == reflect.Slice {
+		usage = "repeat or separate by comma"
+		elemType = resolveType(elemType.Elem())
+	}
+
+	var elemUsage string
+	switch elemType {
+	case reflect.TypeOf(time.Time{}):
+		elemUsage = "RFC3339, 2006-01-02 or relative like \"7d ago\""
+	case reflect.TypeOf(openapi_types.Date{}):
+		elemUsage = "2006-01-02 or relative like \"7d ago\""
+	default:
+		if values, ok := enumValues[elemType]; ok {
+			elemUsage = "one of " + strings.Join(values, "|")
+		}
+	}
+
+	switch {
+	case usage == "":
+		return elemUsage
+	case elemUsage == "":
+		return usage
+	default:
+		return elemUsage + ", " + usage
+	}
+}
+
+// parseRelativeDuration is time.ParseDuration with d (day) and w (week) units
+func parseRelativeDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if f, err := strconv.ParseFloat(n, 64); err == nil {
+				return time.Duration(f * float64(unit)), nil
+			}
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// parseTimeValue parses RFC3339, date, `now` or relative time like `7d ago`, `12h ago`
+func parseTimeValue(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	if s == "now" {
+		return time.Now(), nil
+	}
+
+	if ago, ok := strings.CutSuffix(s, "ago"); ok {
+		d, err := parseRelativeDuration(strings.TrimSpace(ago))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Now().Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("malform time `%s`, expect RFC3339, 2006-01-02 or relative like `7d ago`", s)
+}
+
+func setPointerField(ptrField reflect.Value, values []string) error {
+	newValue := reflect.New(ptrField.Type().Elem()) // NOTE: newValue is a pointer type
+	if err := setValue(newValue.Elem(), values); err != nil {
+		return err
+	}
+
 	ptrField.Set(newValue)
-	switch elemType.Kind() {
+	return nil
+}
+
+// setValue parses values into v, slice takes all values, others take the last one
+func setValue(v reflect.Value, values []string) error {
+	if v.Kind() != reflect.Slice {
+		if len(values) == 0 {
+			return errors.New("no value")
+		}
+		return setScalarValue(v, values[len(values)-1])
+	}
+
+	slice := reflect.MakeSlice(v.Type(), 0, len(values))
+	for _, value := range values {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setScalarValue(elem, value); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+
+	v.Set(slice)
+	return nil
+}
+
+func setScalarValue(v reflect.Value, value string) error {
+	switch v.Interface().(type) {
+	case uuid.UUID:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(id))
+		return nil
+	case time.Time:
+		t, err := parseTimeValue(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case openapi_types.Date:
+		t, err := parseTimeValue(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(openapi_types.Date{Time: t}))
+		return nil
+	}
+
+	switch v.Kind() {
 	case reflect.Bool:
 		if val, err := strconv.ParseBool(value); err != nil {
 			return err
 		} else {
-			newValue.Elem().SetBool(val)
+			v.SetBool(val)
 		}
-	case reflect.Float32:
+	case reflect.Float32, reflect.Float64:
 		if val, err := strconv.ParseFloat(value, 64); err != nil {
 			return err
 		} else {
-			newValue.Elem().SetFloat(val)
+			v.SetFloat(val)
 		}
 	case reflect.String:
-		newValue.Elem().SetString(value)
+		if values, ok := enumValues[v.Type()]; ok {
+			known := false
+			for _, enum := range values {
+				if strings.EqualFold(enum, value) {
+					value, known = enum, true
+					break
+				}
+			}
+			if !known {
+				return fmt.Errorf("unknown value `%s`, expect one of %s", value, strings.Join(values, "|"))
+			}
+		}
+		v.SetString(value)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if val, err := strconv.ParseInt(value, 10, 64); err != nil {
 			return err
 		} else {
-			newValue.Elem().SetInt(val)
+			v.SetInt(val)
 		}
 	default:
-		switch newValue.Interface().(type) {
-		case *uuid.UUID:
-			if id, err := uuid.Parse(value); err != nil {
-				return err
-			} else {
-				newValue.Elem().Set(reflect.ValueOf(id))
-			}
-		default:
-			return fmt.Errorf("unsupported type: %s", elemType.String())
-		}
+		return fmt.Errorf("unsupported type: %s", v.Type().String())
 	}
 
 	return nil
 }
 
-// validateAndGetFieldMap make sure every field of s is ptr to a valid primitive type or struct
-// return a map, which key is tag form name, value is field's reflect.Value
-func validateAndGetFieldMap(s any) (map[string]reflect.Value, error) {
+// isRequiredFormField reports whether field of type t must be set, which is a value without omitempty
+func isRequiredFormField(t reflect.Type, options []string) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice:
+		return false
+	}
+
+	for _, option := range options[1:] {
+		if option == "omitempty" {
+			return false
+		}
+	}
+	return true
+}
+
+// validateAndGetFieldMap make sure every field of s is a valid primitive type or struct, or ptr to it
+// return a map, which key is tag form name, value is field's reflect.Value, and names of required fields
+func validateAndGetFieldMap(s any) (map[string]reflect.Value, []string, error) {
 	valueInfo := resolveElem(reflect.ValueOf(s))
 	typeInfo := valueInfo.Type()
 	ret := make(map[string]reflect.Value)
+	var required []string
 	for i := 0; i < valueInfo.NumField(); i++ {
 		fieldValue := valueInfo.Field(i)
 		fieldType := typeInfo.Field(i)
@@ -166,26 +440,32 @@ func validateAndGetFieldMap(s any) (map[string]reflect.Value, error) {
 		}
 		options := parseOptions(optionsRaw)
 		if len(options) == 0 {
-			return nil, fmt.Errorf("malform field `%s`, tag options is empty", fieldType.Name)
+			return nil, nil, fmt.Errorf("malform field `%s`, tag options is empty", fieldType.Name)
 		}
 
 		name := options[0]
 		switch fieldType.Type.Kind() {
 		case reflect.Pointer, reflect.Array, reflect.Slice:
-		default:
-			return nil, fmt.Errorf("malform field `%s`, type: %s", fieldType.Name, fieldType.Type.String())
-		}
-
-		switch fieldType.Type.Elem().Kind() {
+			switch fieldType.Type.Elem().Kind() {
+			case reflect.Interface,
+				reflect.UnsafePointer,
+				reflect.Pointer:
+				return nil, nil, fmt.Errorf("malform field `%s`, elem type[%d]: %s",
+					fieldType.Name, fieldType.Type.Elem().Kind(), fieldType.Type.Elem().String())
+			}
 		case reflect.Interface,
 			reflect.UnsafePointer,
-			reflect.Pointer:
-			return nil, fmt.Errorf("malform field `%s`, elem type[%d]: %s",
-				fieldType.Name, fieldType.Type.Elem().Kind(), fieldType.Type.Elem().String())
+			reflect.Map,
+			reflect.Chan,
+			reflect.Func:
+			return nil, nil, fmt.Errorf("malform field `%s`, type: %s", fieldType.Name, fieldType.Type.String())
 		}
 
+		if isRequiredFormField(fieldType.Type, options) {
+			required = append(required, name)
+		}
 		ret[name] = fieldValue
 	}
 
-	return ret, nil
+	return ret, required, nil
 }
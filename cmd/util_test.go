@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"github.com/chain710/immich-cli/client"
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 )
 
 type formStruct struct {
@@ -26,3 +36,160 @@ func Test_FlagSetToFormFields(t *testing.T) {
 	require.Equal(t, 98, *s.Int1)
 	require.Equal(t, "test1", *s.String1)
 }
+
+func Test_ListAssets(t *testing.T) {
+	// like the server, every asset after skip is returned, there's no page size
+	var skips []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		skips = append(skips, r.URL.Query().Get("skip"))
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		page := []client.AssetResponseDto{}
+		for i := skip; i < 5; i++ {
+			page = append(page, client.AssetResponseDto{Id: strconv.Itoa(i)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithResponses(server.URL)
+	require.NoError(t, err)
+
+	var ids []string
+	err = listAssets(context.Background(), cli, client.GetAllAssetsParams{}, func(page []client.AssetResponseDto) error {
+		for _, asset := range page {
+			ids = append(ids, asset.Id)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"0", "1", "2", "3", "4"}, ids)
+	require.Equal(t, []string{"0", "5"}, skips)
+
+	// stop early
+	skips = nil
+	err = listAssets(context.Background(), cli, client.GetAllAssetsParams{}, func(page []client.AssetResponseDto) error {
+		return errStopListing
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"0"}, skips)
+}
+
+func Test_PrefixedFormFields(t *testing.T) {
+	flagSet := pflag.NewFlagSet("", pflag.ContinueOnError)
+	addFlagSetByFormFields(&formStruct{}, flagSet)
+	addPrefixedFlagSetByFormFields(&formStruct{}, flagSet, "search.")
+	require.NoError(t, flagSet.Parse([]string{"--int1=1", "--search.int1=2", "--search.string1=s"}))
+
+	var s, prefixed formStruct
+	require.NoError(t, setFormFields(&s, flagSet))
+	require.NoError(t, setPrefixedFormFields(&prefixed, flagSet, "search."))
+	require.Equal(t, 1, *s.Int1)
+	require.Nil(t, s.String1)
+	require.Equal(t, 2, *prefixed.Int1)
+	require.Equal(t, "s", *prefixed.String1)
+}
+
+type typedFormStruct struct {
+	After   *time.Time               `form:"after,omitempty"`
+	Birth   *openapi_types.Date      `form:"birth,omitempty"`
+	Objects *[]string                `form:"objects,omitempty"`
+	Ids     []uuid.UUID              `form:"ids,omitempty"`
+	Type    *client.SearchParamsType `form:"type,omitempty"`
+}
+
+func Test_TypedFormFields(t *testing.T) {
+	flagSet := pflag.NewFlagSet("", pflag.ContinueOnError)
+	addFlagSetByFormFields(&typedFormStruct{}, flagSet)
+	id1, id2 := uuid.New(), uuid.New()
+	require.NoError(t, flagSet.Parse([]string{
+		"--after=7d ago",
+		"--birth=2000-01-02",
+		"--objects=cat, dog",
+		"--objects=sofa",
+		"--ids=" + id1.String() + "," + id2.String(),
+		"--type=video",
+	}))
+
+	var s typedFormStruct
+	require.NoError(t, setFormFields(&s, flagSet))
+	require.WithinDuration(t, time.Now().AddDate(0, 0, -7), *s.After, time.Minute)
+	require.Equal(t, "2000-01-02", s.Birth.String())
+	require.Equal(t, []string{"cat", "dog", "sofa"}, *s.Objects)
+	require.Equal(t, []uuid.UUID{id1, id2}, s.Ids)
+	require.Equal(t, client.SearchParamsTypeVIDEO, *s.Type)
+	require.Contains(t, flagSet.Lookup("type").Usage, "IMAGE|VIDEO")
+
+	for _, args := range [][]string{{"--type=gif"}, {"--after=yesterday"}, {"--ids=1"}} {
+		flagSet := pflag.NewFlagSet("", pflag.ContinueOnError)
+		addFlagSetByFormFields(&typedFormStruct{}, flagSet)
+		require.NoError(t, flagSet.Parse(args))
+		require.Error(t, setFormFields(&typedFormStruct{}, flagSet), args)
+	}
+}
+
+func Test_ParseTimeValue(t *testing.T) {
+	tm, err := parseTimeValue("2023-10-01T08:00:00Z")
+	require.NoError(t, err)
+	require.True(t, tm.Equal(time.Date(2023, 10, 1, 8, 0, 0, 0, time.UTC)))
+
+	tm, err = parseTimeValue("1h30m ago")
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(-90*time.Minute), tm, time.Minute)
+
+	tm, err = parseTimeValue("2w ago")
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().AddDate(0, 0, -14), tm, time.Minute)
+}
+
+func Test_RequiredFormFields(t *testing.T) {
+	flagSet := pflag.NewFlagSet("", pflag.ContinueOnError)
+	addFlagSetByFormFields(&client.GetAuditDeletesParams{}, flagSet)
+	require.Equal(t, []string{"true"}, flagSet.Lookup("entityType").Annotations[cobra.BashCompOneRequiredFlag])
+	require.Equal(t, []string{"true"}, flagSet.Lookup("after").Annotations[cobra.BashCompOneRequiredFlag])
+	require.Nil(t, flagSet.Lookup("userId").Annotations)
+
+	// missing required flag
+	require.NoError(t, flagSet.Parse([]string{"--entityType=asset"}))
+	var params client.GetAuditDeletesParams
+	require.ErrorContains(t, setFormFields(&params, flagSet), "required flag `after` not set")
+
+	require.NoError(t, flagSet.Parse([]string{"--after=2023-10-01T00:00:00Z"}))
+	require.NoError(t, setFormFields(&params, flagSet))
+	require.Equal(t, client.EntityTypeASSET, params.EntityType)
+	require.True(t, params.After.Equal(time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)))
+	require.Nil(t, params.UserId)
+
+	// cobra checks required flags before running
+	var lane client.GetMemoryLaneParams
+	cmd := &cobra.Command{
+		Use: "lane",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setFormFields(&lane, cmd.Flags())
+		},
+	}
+	addFlagSetByFormFields(&client.GetMemoryLaneParams{}, cmd.Flags())
+	cmd.SetArgs([]string{"--day=17"})
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	require.ErrorContains(t, cmd.Execute(), `"month" not set`)
+
+	cmd.SetArgs([]string{"--day=17", "--month=10"})
+	require.NoError(t, cmd.Execute())
+	require.Equal(t, client.GetMemoryLaneParams{Day: 17, Month: 10}, lane)
+}
+
+func Test_ValidateFieldMap(t *testing.T) {
+	_, _, err := validateAndGetFieldMap(&struct {
+		M map[string]string `form:"m"`
+	}{})
+	require.Error(t, err)
+
+	fields, required, err := validateAndGetFieldMap(&struct {
+		A int     `form:"a"`
+		B string  `form:"b,omitempty"`
+		C *string `form:"c"`
+	}{})
+	require.NoError(t, err)
+	require.Len(t, fields, 3)
+	require.Equal(t, []string{"a"}, required)
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/chain710/immich-cli/cmd"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"os"
+	"os/signal"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -15,6 +19,8 @@ var logLevel string
 var apiURL string
 var apiKey string
 var cfgFile string
+var journalFile string
+var maxDelete int
 
 //go:generate oapi-codegen -generate "types,client" -package client -o client/immich.auto_generated.go https://raw.githubusercontent.com/immich-app/immich/v1.82.0/server/immich-openapi-specs.json
 
@@ -59,6 +65,8 @@ func main() {
 	bindViperFlags.StringVarP(&logLevel, cmd.ViperKey_LogLevel, "L", log.InfoLevel.String(), "log level: debug|info|warning|error")
 	bindViperFlags.StringVarP(&apiURL, cmd.ViperKey_API, "a", "", "api address, like: https://immich.example.com/api")
 	bindViperFlags.StringVarP(&apiKey, cmd.ViperKey_APIKey, "", "", "api key obtained from immich admin")
+	bindViperFlags.StringVarP(&journalFile, cmd.ViperKey_Journal, "", "", "journal file of destructive operations (default is $HOME/.immich_journal.jsonl)")
+	bindViperFlags.IntVar(&maxDelete, cmd.ViperKey_MaxDelete, 0, "abort when more assets than this are going to be deleted, 0 means no limit")
 	cobra.CheckErr(viper.BindPFlags(bindViperFlags))
 	cobra.OnInitialize(func() {
 		initConfig(bindViperFlags)
@@ -69,16 +77,29 @@ func main() {
 	// add sub commands
 	rootCommand.AddCommand(
 		cmd.GetAssetsCmd(),
+		cmd.GetAssetCmd(),
 		cmd.DeleteDuplicatesCmd(),
 		cmd.DeleteAssetCmd(),
+		cmd.FindDuplicatesCmd(),
+		cmd.UndoCmd(),
+		cmd.TrashCmd(),
+		cmd.ArchiveAssetCmd(),
+		cmd.TagAssetCmd(),
+		cmd.DownloadAssetCmd(),
 	)
 	persistentFlags := rootCommand.PersistentFlags()
 	persistentFlags.StringVar(&cfgFile, "config", "", "config file (default is $HOME/.immich)")
 	persistentFlags.AddFlagSet(bindViperFlags)
 	cobra.CheckErr(rootCommand.MarkPersistentFlagRequired(cmd.ViperKey_API))
 	cobra.CheckErr(rootCommand.MarkPersistentFlagRequired(cmd.ViperKey_APIKey))
-	if err := rootCommand.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := rootCommand.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(-1)
 	}
 }